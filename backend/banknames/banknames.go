@@ -0,0 +1,31 @@
+// Package banknames normalizes the free-text bank names scraped from bank
+// sites, so products, banks and aliases are compared in one form.
+package banknames
+
+import (
+	"strings"
+	"unicode"
+)
+
+// legalSuffixes are dropped from the end of a bank name before comparing.
+var legalSuffixes = map[string]bool{
+	"ltd": true, "limited": true, "pvt": true, "private": true, "plc": true,
+	"inc": true, "co": true, "company": true, "corp": true, "corporation": true,
+}
+
+// Normalize reduces a bank name to the form names are compared in: lower
+// case, punctuation removed, and trailing legal suffixes and a trailing
+// "bank" dropped, so "HDFC Bank Ltd." and "HDFC" both become "hdfc". Names
+// where "bank" is not last, such as "Bank of Baroda", keep it.
+func Normalize(name string) string {
+	tokens := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for len(tokens) > 1 && legalSuffixes[tokens[len(tokens)-1]] {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) > 1 && tokens[len(tokens)-1] == "bank" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return strings.Join(tokens, " ")
+}
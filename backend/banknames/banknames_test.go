@@ -0,0 +1,37 @@
+package banknames
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"HDFC Bank", "hdfc"},
+		{"HDFC Bank Ltd.", "hdfc"},
+		{"  hdfc   BANK  limited ", "hdfc"},
+		{"HDFC", "hdfc"},
+		{"ICICI Bank Pvt. Ltd.", "icici"},
+		{"Kotak Mahindra Bank", "kotak mahindra"},
+		{"Bank of Baroda", "bank of baroda"},
+		{"State Bank of India", "state bank of india"},
+		{"Bajaj Finance Limited", "bajaj finance"},
+		{"Bajaj Finance Corp.", "bajaj finance"},
+		{"AU Small Finance Bank", "au small finance"},
+		{"IDFC FIRST Bank", "idfc first"},
+		{"Au-Small_Finance/Bank", "au small finance"},
+		{"Bank", "bank"},
+		{"Limited", "limited"},
+		{"Bank Ltd", "bank"},
+		{"Société Générale", "société générale"},
+		{"", ""},
+		{"...", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.name); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,10 @@ echo "Building uploadcsv function..."
 cd uploadcsv
 GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda.norpc -o bootstrap main.go
 cd ..
+echo "Building products function..."
+cd products
+GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -tags lambda.norpc -o bootstrap main.go
+cd ..
 
 cd ..
 
@@ -0,0 +1,81 @@
+// Command matchbench times the Go and set-based matching modes against the
+// configured database and checks that the set-based join uses the user
+// indexes. Every run happens inside a transaction that is rolled back, so the
+// matches table is left untouched.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/svc"
+)
+
+func main() {
+	runs := flag.Int("runs", 3, "number of runs per mode")
+	modes := flag.String("modes", "go,sql", "comma separated modes to benchmark")
+	flag.Parse()
+
+	database.DBConnect()
+
+	plan, err := svc.ExplainSetBasedMatching()
+	if err != nil {
+		slog.Error("Failed to explain set-based matching", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("set-based plan indexes: %v (uses user index: %t)\n", plan.Indexes, plan.UsesUserIndex)
+
+	for _, mode := range splitModes(*modes) {
+		var total time.Duration
+		for i := 0; i < *runs; i++ {
+			elapsed, result, err := benchmarkRun(mode)
+			if err != nil {
+				slog.Error("Benchmark run failed", "mode", mode, "run", i+1, "error", err)
+				os.Exit(1)
+			}
+			total += elapsed
+			fmt.Printf("mode=%s run=%d elapsed=%s products=%d upserted=%d invalidated=%d\n",
+				mode, i+1, elapsed, result.Products, result.Upserted, result.Invalidated)
+		}
+		fmt.Printf("mode=%s avg=%s\n", mode, total/time.Duration(*runs))
+	}
+
+	if !plan.UsesUserIndex {
+		fmt.Println("WARNING: set-based join does not use idx_users_credit_score or idx_users_income")
+		os.Exit(2)
+	}
+}
+
+// benchmarkRun swaps database.DB for a transaction for the duration of one
+// run and rolls it back afterwards.
+func benchmarkRun(mode svc.MatchMode) (time.Duration, svc.MatchRunResult, error) {
+	db := database.DB
+	tx := db.Begin()
+	if tx.Error != nil {
+		return 0, svc.MatchRunResult{}, tx.Error
+	}
+	database.DB = tx
+	defer func() {
+		tx.Rollback()
+		database.DB = db
+	}()
+
+	start := time.Now()
+	result, err := svc.RunMatching(mode)
+	return time.Since(start), result, err
+}
+
+func splitModes(s string) []svc.MatchMode {
+	var modes []svc.MatchMode
+	for _, m := range strings.Split(s, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			modes = append(modes, svc.MatchMode(m))
+		}
+	}
+	return modes
+}
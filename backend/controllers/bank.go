@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func ListBanks(c *gin.Context) {
+	banks, err := svc.ListBanks()
+	if err != nil {
+		slog.Error("ListBanks: Query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load banks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"banks": banks})
+}
+
+func GetBank(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bank id"})
+		return
+	}
+
+	bank, err := svc.GetBank(id)
+	if err != nil {
+		bankError(c, "GetBank", "Failed to load bank", err)
+		return
+	}
+	c.JSON(http.StatusOK, bank)
+}
+
+func CreateBank(c *gin.Context) {
+	var in svc.BankInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bank body"})
+		return
+	}
+
+	bank, err := svc.CreateBank(in)
+	if err != nil {
+		bankError(c, "CreateBank", "Failed to create bank", err)
+		return
+	}
+	c.JSON(http.StatusCreated, bank)
+}
+
+func UpdateBank(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bank id"})
+		return
+	}
+
+	var in svc.BankInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bank body"})
+		return
+	}
+
+	bank, err := svc.UpdateBank(id, in)
+	if err != nil {
+		bankError(c, "UpdateBank", "Failed to update bank", err)
+		return
+	}
+	c.JSON(http.StatusOK, bank)
+}
+
+// ListBankMerges returns merge proposals, pending ones by default; pass
+// ?status=all for every proposal.
+func ListBankMerges(c *gin.Context) {
+	status := models.BankMergeStatus(c.DefaultQuery("status", string(models.BankMergePending)))
+	if status == "all" {
+		status = ""
+	}
+
+	merges, err := svc.ListBankMerges(status)
+	if err != nil {
+		slog.Error("ListBankMerges: Query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bank merges"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(merges), "merges": merges})
+}
+
+func ProposeBankMerges(c *gin.Context) {
+	result, err := svc.ProposeBankMerges()
+	if err != nil {
+		slog.Error("ProposeBankMerges: Failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to propose bank merges"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func ConfirmBankMerge(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge id"})
+		return
+	}
+
+	var in svc.BankMergeConfirmation
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid confirmation body"})
+			return
+		}
+	}
+
+	merge, linked, err := svc.ConfirmBankMerge(id, in)
+	if err != nil {
+		bankError(c, "ConfirmBankMerge", "Failed to confirm bank merge", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"merge": merge, "products_linked": linked})
+}
+
+func RejectBankMerge(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge id"})
+		return
+	}
+
+	merge, err := svc.RejectBankMerge(id)
+	if err != nil {
+		bankError(c, "RejectBankMerge", "Failed to reject bank merge", err)
+		return
+	}
+	c.JSON(http.StatusOK, merge)
+}
+
+func bankError(c *gin.Context, op, failure string, err error) {
+	var invalid *svc.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid bank", "fields": invalid.Fields})
+	case errors.Is(err, svc.ErrBankNameConflict), errors.Is(err, svc.ErrBankMergeResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	default:
+		slog.Error(op+": Failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+	}
+}
@@ -22,7 +22,7 @@ func UploadCSVUsers(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required"})
 		return
 	}
-	slog.Info("got the file with the size", file.Size)
+	slog.Info("got the file", "size", file.Size)
 	f, err := file.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
@@ -97,6 +97,20 @@ func UploadCSVUsers(c *gin.Context) {
 		creditScore, _ := strconv.Atoi(row[4])
 		income, _ := strconv.ParseFloat(row[3], 64)
 		employmentStatus := row[5]
+		// Affordability columns are optional so older exports still import.
+		obligations, _ := strconv.ParseFloat(optionalColumn(row, 7), 64)
+		requestedAmount, _ := strconv.ParseFloat(optionalColumn(row, 8), 64)
+		requestedTenure, _ := strconv.Atoi(optionalColumn(row, 9))
+		// Location columns are optional too; products with a service area
+		// reject users without one.
+		pincode := optionalColumn(row, 10)
+		city := optionalColumn(row, 11)
+		cityTier, _ := strconv.Atoi(optionalColumn(row, 12))
+		// Unknown loan types are dropped rather than rejecting the row.
+		loanType := models.LoanType(strings.ToLower(optionalColumn(row, 13)))
+		if !models.ValidLoanType(loanType) {
+			loanType = ""
+		}
 		user := models.User{
 			ID:               id,
 			Name:             row[1],
@@ -105,6 +119,15 @@ func UploadCSVUsers(c *gin.Context) {
 			CreditScore:      creditScore,
 			MonthlyIncome:    income,
 			EmploymentStatus: employmentStatus,
+
+			MonthlyObligations:    obligations,
+			RequestedAmount:       requestedAmount,
+			RequestedTenureMonths: requestedTenure,
+			RequestedLoanType:     loanType,
+
+			Pincode:  pincode,
+			City:     city,
+			CityTier: cityTier,
 		}
 
 		batch = append(batch, user)
@@ -149,3 +172,10 @@ func UploadCSVUsers(c *gin.Context) {
 		"duplicate_email_count": duplicateCount,
 	})
 }
+
+func optionalColumn(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
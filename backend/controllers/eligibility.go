@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type eligibilityRequest struct {
+	Age              int     `json:"age" binding:"required,gte=18,lte=100"`
+	MonthlyIncome    float64 `json:"monthly_income" binding:"gte=0"`
+	CreditScore      int     `json:"credit_score" binding:"required,gte=300,lte=900"`
+	EmploymentStatus string  `json:"employment_status"`
+
+	MonthlyObligations    float64 `json:"monthly_obligations" binding:"gte=0"`
+	RequestedAmount       float64 `json:"requested_amount" binding:"gte=0"`
+	RequestedTenureMonths int     `json:"requested_tenure_months" binding:"gte=0,lte=480"`
+	RequestedLoanType     string  `json:"requested_loan_type" binding:"omitempty,oneof=personal home gold business education"`
+
+	Pincode  string `json:"pincode" binding:"omitempty,numeric,len=6"`
+	City     string `json:"city"`
+	CityTier int    `json:"city_tier" binding:"gte=0,lte=3"`
+
+	Rank string `json:"rank"`
+}
+
+// CheckEligibility evaluates an ad-hoc profile against the catalog with the
+// same rules as persisted matching. Nothing is written to the database.
+func CheckEligibility(c *gin.Context) {
+	var req eligibilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ranker, err := matching.LookupRanker(req.Rank)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "strategies": matching.RankerNames()})
+		return
+	}
+
+	products, err := svc.ListActiveProducts()
+	if err != nil {
+		slog.Error("CheckEligibility: Failed to load products", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return
+	}
+
+	user := models.User{
+		Age:              req.Age,
+		MonthlyIncome:    req.MonthlyIncome,
+		CreditScore:      req.CreditScore,
+		EmploymentStatus: req.EmploymentStatus,
+
+		MonthlyObligations:    req.MonthlyObligations,
+		RequestedAmount:       req.RequestedAmount,
+		RequestedTenureMonths: req.RequestedTenureMonths,
+		RequestedLoanType:     models.LoanType(req.RequestedLoanType),
+
+		Pincode:  req.Pincode,
+		City:     req.City,
+		CityTier: req.CityTier,
+	}
+	offers := matching.EligibleOffers(&user, products, ranker)
+
+	c.JSON(http.StatusOK, gin.H{
+		"products_evaluated": len(products),
+		"eligible_count":     len(offers),
+		"offers":             offers,
+	})
+}
+
+func UserOffers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	ranker, err := matching.LookupRanker(c.Query("rank"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "strategies": matching.RankerNames()})
+		return
+	}
+
+	user, err := svc.GetUser(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("UserOffers: Failed to load user", "user_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		return
+	}
+
+	products, err := svc.ListActiveProducts()
+	if err != nil {
+		slog.Error("UserOffers: Failed to load products", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return
+	}
+
+	offers := matching.EligibleOffers(user, products, ranker)
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":        user.ID,
+		"eligible_count": len(offers),
+		"offers":         offers,
+	})
+}
+
+func ListRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"rules":   matching.RuleNames(),
+		"rankers": matching.RankerNames(),
+	})
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+)
+
+// FairnessReport returns match rates and score distributions per age band and
+// employment status. ?format=csv exports the groups as CSV and ?threshold=
+// overrides the configured disparity threshold.
+func FairnessReport(c *gin.Context) {
+	var threshold float64
+	if v := c.Query("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 || t >= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be between 0 and 1"})
+			return
+		}
+		threshold = t
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'json' or 'csv'"})
+		return
+	}
+
+	report, err := svc.BuildFairnessReport(threshold)
+	if err != nil {
+		slog.Error("FairnessReport: Failed to build report", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build fairness report"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="fairness-`+report.GeneratedAt.Format("20060102-150405")+`.csv"`)
+	w := csv.NewWriter(c.Writer)
+	w.Write(svc.FairnessCSVHeader)
+	for _, g := range report.Groups {
+		w.Write(g.CSVRow())
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		slog.Error("FairnessReport: CSV write failed", "error", err)
+	}
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/BadadheVed/clickpe/job"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func RematchProduct(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	product, err := svc.GetProduct(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("RematchProduct: Failed to load product", "product_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product"})
+		return
+	}
+
+	result, err := svc.RematchProduct(product, svc.TriggerManual)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rematch product"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type runMatchingRequest struct {
+	Mode    svc.MatchMode `json:"mode"`
+	Workers int           `json:"workers"`
+}
+
+func RunMatching(c *gin.Context) {
+	var req runMatchingRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Mode != "" && req.Mode != svc.MatchModeGo && req.Mode != svc.MatchModeSQL && req.Mode != svc.MatchModeParallel {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be 'go', 'sql' or 'parallel'"})
+		return
+	}
+	if req.Workers < 0 || req.Workers > job.MaxMatchWorkers {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workers must be between 1 and " + strconv.Itoa(job.MaxMatchWorkers)})
+		return
+	}
+
+	// Parallel runs continue in the background; poll the progress endpoint.
+	if req.Mode == svc.MatchModeParallel {
+		progress, err := job.StartParallelMatching(req.Workers)
+		if err != nil {
+			slog.Error("RunMatching: Failed to start parallel run", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start matching"})
+			return
+		}
+		c.JSON(http.StatusAccepted, progress.Snapshot())
+		return
+	}
+
+	result, err := svc.RunMatching(req.Mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run matching"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func MatchPlan(c *gin.Context) {
+	plan, err := svc.ExplainSetBasedMatching()
+	if err != nil {
+		slog.Error("MatchPlan: EXPLAIN failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to explain matching query"})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+type withdrawMatchRequest struct {
+	Reason string `json:"reason"`
+}
+
+func WithdrawMatch(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match id"})
+		return
+	}
+
+	var req withdrawMatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	match, err := svc.WithdrawMatch(id, req.Reason)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("WithdrawMatch: Failed", "match_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw match"})
+		return
+	}
+	c.JSON(http.StatusOK, match)
+}
+
+func SweepMatches(c *gin.Context) {
+	result, err := svc.SweepMatches()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sweep matches"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type matchListing struct {
+	models.Match
+	BankName     string   `json:"bank_name"`
+	ProductName  string   `json:"product_name"`
+	InterestRate string   `json:"interest_rate"`
+	MinAPR       *float64 `json:"min_apr"`
+	MaxAPR       *float64 `json:"max_apr"`
+}
+
+func ListUserMatches(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	status := models.MatchStatus(c.DefaultQuery("status", string(models.MatchStatusActive)))
+	if status == "all" {
+		status = ""
+	}
+
+	matches, err := svc.ListUserMatches(id, status)
+	if err != nil {
+		slog.Error("ListUserMatches: Query failed", "user_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load matches"})
+		return
+	}
+
+	listings := make([]matchListing, 0, len(matches))
+	for _, m := range matches {
+		listings = append(listings, matchListing{
+			Match:        m,
+			BankName:     m.LoanProduct.DisplayBankName(),
+			ProductName:  m.LoanProduct.ProductName,
+			InterestRate: m.LoanProduct.InterestRate,
+			MinAPR:       m.LoanProduct.MinAPR,
+			MaxAPR:       m.LoanProduct.MaxAPR,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": id, "count": len(listings), "matches": listings})
+}
+
+// GetMatch returns a single match including its criteria snapshot and the run
+// that produced it, for reconstructing a past decision.
+func GetMatch(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid match id"})
+		return
+	}
+
+	match, err := svc.GetMatch(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("GetMatch: Query failed", "match_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load match"})
+		return
+	}
+
+	var run *models.MatchRun
+	if match.RunID != nil {
+		run, err = svc.GetMatchRun(*match.RunID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error("GetMatch: Run query failed", "run_id", *match.RunID, "error", err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"match": match, "run": run})
+}
+
+func ListMatchRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	runs, err := svc.ListMatchRuns(limit)
+	if err != nil {
+		slog.Error("ListMatchRuns: Query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load match runs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+func GetMatchRun(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run id"})
+		return
+	}
+
+	run, err := svc.GetMatchRun(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match run not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("GetMatchRun: Query failed", "run_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load match run"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+func MatchRunProgress(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run id"})
+		return
+	}
+
+	progress, ok := job.MatchRunProgress(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match run is not in progress"})
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+func CancelMatchRun(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run id"})
+		return
+	}
+
+	if !job.CancelMatchRun(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Match run is not in progress"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"run_id": id, "status": "cancelling"})
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListProducts searches the catalog. Filters: q (full text over product and
+// bank name), bank, bank_id, min_rate, max_rate, max_credit_score,
+// max_income, loan_type, active and status. Results are sorted by sort (prefix "-" for
+// descending) and paged with limit and the next_cursor of the previous page.
+func ListProducts(c *gin.Context) {
+	search := svc.ProductSearch{
+		Query:    c.Query("q"),
+		Bank:     c.Query("bank"),
+		Status:   models.ProductStatus(c.Query("status")),
+		LoanType: models.LoanType(c.Query("loan_type")),
+		Sort:     c.Query("sort"),
+		Cursor:   c.Query("cursor"),
+	}
+
+	var invalid []string
+	if v := c.Query("bank_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			invalid = append(invalid, "bank_id")
+		}
+		search.BankID = &id
+	}
+	floatParam := func(key string) *float64 {
+		v := c.Query(key)
+		if v == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			invalid = append(invalid, key)
+			return nil
+		}
+		return &f
+	}
+	search.MinRate = floatParam("min_rate")
+	search.MaxRate = floatParam("max_rate")
+	search.MaxIncome = floatParam("max_income")
+	if v := c.Query("max_credit_score"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			invalid = append(invalid, "max_credit_score")
+		}
+		search.MaxCreditScore = &n
+	}
+	if !models.ValidLoanType(search.LoanType) {
+		invalid = append(invalid, "loan_type")
+	}
+	if v := c.Query("active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid = append(invalid, "active")
+		}
+		search.Active = &b
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > svc.MaxProductPageSize {
+			invalid = append(invalid, "limit")
+		}
+		search.Limit = n
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "fields": invalid})
+		return
+	}
+
+	page, err := svc.SearchProducts(search)
+	var badSort *svc.ValidationError
+	switch {
+	case errors.Is(err, svc.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor for this search"})
+	case errors.As(err, &badSort):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters", "fields": badSort.Fields})
+	case err != nil:
+		slog.Error("ListProducts: Query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+	default:
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+func GetProduct(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	product, err := svc.GetProduct(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("GetProduct: Query failed", "product_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product"})
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
+
+func CreateProduct(c *gin.Context) {
+	var in svc.ProductInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product body"})
+		return
+	}
+
+	source, ok := changeSource(c)
+	if !ok {
+		return
+	}
+	product, err := svc.CreateProduct(in, source)
+	if err != nil {
+		productError(c, "CreateProduct", "Failed to create product", err)
+		return
+	}
+	c.JSON(http.StatusCreated, product)
+}
+
+func UpdateProduct(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	var in svc.ProductInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product body"})
+		return
+	}
+
+	source, ok := changeSource(c)
+	if !ok {
+		return
+	}
+	product, err := svc.UpdateProduct(id, in, source)
+	if err != nil {
+		productError(c, "UpdateProduct", "Failed to update product", err)
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
+
+func DeleteProduct(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	if err := svc.DeleteProduct(id); err != nil {
+		productError(c, "DeleteProduct", "Failed to delete product", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// changeSource reads the optional X-Change-Source header recorded in product
+// history. Requests default to "api"; admin tools send "admin".
+func changeSource(c *gin.Context) (models.ProductChangeSource, bool) {
+	source := models.ProductChangeSource(c.GetHeader("X-Change-Source"))
+	if source == "" {
+		return models.ProductSourceAPI, true
+	}
+	if !svc.ValidChangeSource(source) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Change-Source must be 'api' or 'admin'"})
+		return "", false
+	}
+	return source, true
+}
+
+func ProductHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	history, err := svc.ListProductHistory(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("ProductHistory: Query failed", "product_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product history"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"product_id": id, "history": history})
+}
+
+// productError maps product service errors to responses.
+func productError(c *gin.Context, op, failure string, err error) {
+	var invalid *svc.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid product", "fields": invalid.Fields})
+	case errors.Is(err, svc.ErrProductURLConflict), errors.Is(err, svc.ErrProductNotInReview), errors.Is(err, svc.ErrProductMerged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	default:
+		slog.Error(op+": Failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+	}
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BadadheVed/clickpe/job"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+)
+
+const maxBulkProducts = 5000
+
+// BulkUpsertProducts accepts a JSON array of crawled products and upserts
+// them on product_url with a worker pool. Each entry is reported as created,
+// updated, unchanged or rejected, in request order.
+func BulkUpsertProducts(c *gin.Context) {
+	var raw []json.RawMessage
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be a JSON array of products"})
+		return
+	}
+	if len(raw) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No products in payload"})
+		return
+	}
+	if len(raw) > maxBulkProducts {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "At most 5000 products per request"})
+		return
+	}
+
+	results := make([]job.ProductResult, 0, len(raw))
+	entries := make([]job.ProductEntry, 0, len(raw))
+	seen := map[string]int{}
+	for i, item := range raw {
+		var in svc.ProductInput
+		dec := json.NewDecoder(bytes.NewReader(item))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			results = append(results, job.ProductResult{Index: i, Status: svc.UpsertRejected, Error: "Does not match product schema: " + err.Error()})
+			continue
+		}
+		url := strings.TrimSpace(in.ProductURL)
+		if first, dup := seen[url]; dup && url != "" {
+			results = append(results, job.ProductResult{Index: i, ProductURL: url, Status: svc.UpsertRejected,
+				Error: "Duplicate product_url; first seen at index " + strconv.Itoa(first)})
+			continue
+		}
+		seen[url] = i
+		entries = append(entries, job.ProductEntry{Index: i, Input: in})
+	}
+
+	const workerCount = 5
+	const batchSize = 50
+	jobs := make(chan []job.ProductEntry, len(entries)/batchSize+1)
+	out := make(chan []job.ProductResult, len(entries)/batchSize+1)
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go job.ProductWorker(i, jobs, out, &wg)
+	}
+	for start := 0; start < len(entries); start += batchSize {
+		jobs <- entries[start:min(start+batchSize, len(entries))]
+	}
+	close(jobs)
+	wg.Wait()
+	close(out)
+
+	for batch := range out {
+		results = append(results, batch...)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+
+	counts := map[svc.UpsertOutcome]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"received":  len(raw),
+		"created":   counts[svc.UpsertCreated],
+		"updated":   counts[svc.UpsertUpdated],
+		"unchanged": counts[svc.UpsertUnchanged],
+		"rejected":  counts[svc.UpsertRejected],
+		"results":   results,
+	})
+}
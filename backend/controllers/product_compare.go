@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CompareProducts returns 2 to 5 products side by side, priced for the
+// requested loan and, when a user is given, with their eligibility for each.
+func CompareProducts(c *gin.Context) {
+	var req svc.CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comparison request"})
+		return
+	}
+
+	comparison, err := svc.CompareProducts(req)
+	var invalid *svc.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid comparison request", "fields": invalid.Fields})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case err != nil:
+		slog.Error("CompareProducts: Comparison failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare products"})
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
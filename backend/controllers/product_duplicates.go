@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListDuplicateProducts returns groups of products that look like the same
+// offer published on different URLs.
+func ListDuplicateProducts(c *gin.Context) {
+	groups, err := svc.FindDuplicateProducts()
+	if err != nil {
+		slog.Error("ListDuplicateProducts: Detection failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicate products"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(groups), "groups": groups})
+}
+
+type mergeProductsRequest struct {
+	DuplicateIDs []uuid.UUID `json:"duplicate_ids" binding:"required"`
+	Note         string      `json:"note"`
+}
+
+// MergeProducts merges the listed duplicates into the product in the path.
+func MergeProducts(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	var req mergeProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge body"})
+		return
+	}
+
+	result, err := svc.MergeProducts(id, req.DuplicateIDs, req.Note)
+	if err != nil {
+		productError(c, "MergeProducts", "Failed to merge products", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
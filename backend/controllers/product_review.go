@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListProductReviews returns the products held for review, each with its
+// diff against the last approved version.
+func ListProductReviews(c *gin.Context) {
+	reviews, err := svc.ListProductReviews()
+	if err != nil {
+		slog.Error("ListProductReviews: Query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product reviews"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(reviews), "reviews": reviews})
+}
+
+func GetProductReview(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	review, err := svc.GetProductReview(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("GetProductReview: Query failed", "product_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product review"})
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+type reviewDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+func ApproveProduct(c *gin.Context) {
+	reviewDecision(c, "ApproveProduct", "Failed to approve product", svc.ApproveProduct)
+}
+
+func RejectProduct(c *gin.Context) {
+	reviewDecision(c, "RejectProduct", "Failed to reject product", svc.RejectProduct)
+}
+
+func reviewDecision(c *gin.Context, op, failure string, decide func(uuid.UUID, string) (*models.LoanProduct, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	var req reviewDecisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review body"})
+			return
+		}
+	}
+
+	product, err := decide(id, req.Note)
+	if err != nil {
+		productError(c, op, failure, err)
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+)
+
+// CoverageReport lists users with no active match, grouped by the criterion
+// that blocks them most often, with the nearest product for each segment.
+func CoverageReport(c *gin.Context) {
+	report, err := svc.BuildCoverageReport()
+	if err != nil {
+		slog.Error("CoverageReport: Failed to build report", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build coverage report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WhatIfProduct simulates proposed criteria for a product against the live
+// users table. Nothing is persisted.
+func WhatIfProduct(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product id"})
+		return
+	}
+
+	var overrides svc.CriteriaOverrides
+	if err := c.ShouldBindJSON(&overrides); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid criteria overrides"})
+		return
+	}
+
+	product, err := svc.GetProduct(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("WhatIfProduct: Failed to load product", "product_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product"})
+		return
+	}
+
+	result, err := svc.SimulateCriteria(product, overrides)
+	var invalid *svc.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid criteria overrides", "fields": invalid.Fields})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to simulate criteria"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -4,7 +4,6 @@ import (
 	"log/slog"
 	"os"
 
-	"github.com/BadadheVed/clickpe/models"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -16,7 +15,7 @@ func DBConnect() {
 	slog.Info("Loading ENV")
 	err := godotenv.Load()
 	if err != nil {
-		slog.Error("Error loading .env file", err)
+		slog.Error("Error loading .env file", "error", err)
 	}
 	slog.Info("--------ENV loaded successfully-------")
 	dsn := os.Getenv("DATABASE_URL")
@@ -26,21 +25,15 @@ func DBConnect() {
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		slog.Error("Failed to connect to database:", err)
+		slog.Error("Failed to connect to database", "error", err)
 	}
 
 	DB = db
 	slog.Info("Database connected successfully")
 
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.LoanProduct{},
-		&models.Match{},
-	)
-
-	if err != nil {
-		slog.Error("Failed to auto-migrate tables:", err)
+	if err := Migrate(db); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		return
 	}
-
 	slog.Info("Tables migrated successfully")
 }
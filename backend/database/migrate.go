@@ -0,0 +1,107 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/BadadheVed/clickpe/models"
+	"gorm.io/gorm"
+)
+
+// Migrate brings the schema up to date. The Lambda functions run it on cold
+// start too, so both deployments migrate the same tables the same way.
+func Migrate(db *gorm.DB) error {
+	// Duplicates must go before AutoMigrate adds the unique match index.
+	if err := dedupeMatches(db); err != nil {
+		return fmt.Errorf("dedupe matches: %w", err)
+	}
+	err := db.AutoMigrate(
+		&models.User{},
+		&models.Bank{},
+		&models.LoanProduct{},
+		&models.Match{},
+		&models.MatchRun{},
+		&models.ProductHistory{},
+		&models.BankMerge{},
+	)
+	if err != nil {
+		return fmt.Errorf("auto-migrate tables: %w", err)
+	}
+	if err := createProductSearchIndexes(db); err != nil {
+		return fmt.Errorf("create product search indexes: %w", err)
+	}
+	if err := backfillApprovedVersions(db); err != nil {
+		return fmt.Errorf("backfill approved product versions: %w", err)
+	}
+	return nil
+}
+
+// dedupeMatches collapses duplicate (user_id, product_id) rows so the unique
+// index can be created. The earliest row survives, keeping its MatchedAt, and
+// inherits IsNotified if any duplicate had already been notified.
+func dedupeMatches(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Match{}) {
+		return nil
+	}
+
+	err := db.Exec(`
+UPDATE matches k SET is_notified = true
+WHERE NOT k.is_notified
+  AND EXISTS (SELECT 1 FROM matches d
+              WHERE d.user_id = k.user_id AND d.product_id = k.product_id AND d.is_notified)`).Error
+	if err != nil {
+		return err
+	}
+
+	res := db.Exec(`
+DELETE FROM matches m USING matches d
+WHERE m.user_id = d.user_id
+  AND m.product_id = d.product_id
+  AND (m.matched_at > d.matched_at OR (m.matched_at = d.matched_at AND m.id > d.id))`)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		slog.Info("Removed duplicate matches", "count", res.RowsAffected)
+	}
+	return nil
+}
+
+// backfillApprovedVersions approves the latest criteria version of products
+// already in matching, so the review workflow only holds back later changes.
+func backfillApprovedVersions(db *gorm.DB) error {
+	res := db.Exec(`
+UPDATE loan_products p SET approved_version = h.version
+FROM (SELECT product_id, max(version) AS version FROM product_histories GROUP BY product_id) h
+WHERE h.product_id = p.id AND p.approved_version = 0 AND p.status = ?`, models.ProductStatusActive)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		slog.Info("Backfilled approved product versions", "count", res.RowsAffected)
+	}
+	return nil
+}
+
+// productSearchIndexes back the catalog search. Expressions must match the
+// ones svc.SearchProducts filters and sorts on; AutoMigrate cannot declare
+// expression or GIN indexes.
+var productSearchIndexes = []string{
+	`CREATE INDEX IF NOT EXISTS idx_loan_products_search ON loan_products
+	   USING gin (to_tsvector('simple', coalesce(product_name, '') || ' ' || coalesce(bank_name, '')))`,
+	`CREATE INDEX IF NOT EXISTS idx_loan_products_bank_name_lower ON loan_products (lower(bank_name))`,
+	`CREATE INDEX IF NOT EXISTS idx_loan_products_sort_created ON loan_products (created_at, id)`,
+	`CREATE INDEX IF NOT EXISTS idx_loan_products_sort_updated ON loan_products (COALESCE(updated_at, created_at), id)`,
+	`CREATE INDEX IF NOT EXISTS idx_loan_products_sort_rate ON loan_products (COALESCE(min_apr, 9999.99), id)`,
+	`CREATE INDEX IF NOT EXISTS idx_loan_products_sort_credit ON loan_products (min_credit_score, id)`,
+	`CREATE INDEX IF NOT EXISTS idx_loan_products_sort_income ON loan_products (min_monthly_income, id)`,
+}
+
+func createProductSearchIndexes(db *gorm.DB) error {
+	for _, stmt := range productSearchIndexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package job
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/svc"
+)
+
+// ProposeBankMerges periodically links products to confirmed banks and
+// refreshes the merge proposals for the remaining bank names.
+func ProposeBankMerges(interval time.Duration) {
+	slog.Info("Bank merge proposer started", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		res, err := svc.ProposeBankMerges()
+		if err != nil {
+			slog.Error("Bank merge proposer failed", "error", err)
+		} else if res.Linked > 0 || res.Proposed > 0 || res.Cleared > 0 {
+			slog.Info("Bank merge proposer done", "linked", res.Linked, "proposed", res.Proposed, "cleared", res.Cleared)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,25 @@
+package job
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/svc"
+)
+
+// SweepMatches periodically expires matches whose validity window elapsed or
+// whose user or product no longer satisfies the criteria.
+func SweepMatches(interval time.Duration) {
+	slog.Info("Match sweeper started", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		res, err := svc.SweepMatches()
+		if err != nil {
+			slog.Error("Match sweeper failed", "error", err)
+			continue
+		}
+		slog.Info("Match sweeper done", "window_expired", res.WindowExpired, "criteria_expired", res.CriteriaExpired)
+	}
+}
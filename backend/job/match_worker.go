@@ -0,0 +1,194 @@
+package job
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMatchWorkers = 4
+	MaxMatchWorkers     = 32
+
+	// shardsPerWorker keeps workers busy when some ID ranges are denser than others.
+	shardsPerWorker = 4
+)
+
+// MatchProgress is updated by the workers of a parallel match run as each
+// page of users completes.
+type MatchProgress struct {
+	RunID      uuid.UUID
+	Workers    int
+	Shards     int
+	TotalUsers int64
+	StartedAt  time.Time
+
+	usersProcessed atomic.Int64
+	upserted       atomic.Int64
+	invalidated    atomic.Int64
+	shardsDone     atomic.Int64
+	cancelling     atomic.Bool
+}
+
+type MatchProgressSnapshot struct {
+	RunID          uuid.UUID `json:"run_id"`
+	Workers        int       `json:"workers"`
+	Shards         int       `json:"shards"`
+	ShardsDone     int64     `json:"shards_done"`
+	TotalUsers     int64     `json:"total_users"`
+	UsersProcessed int64     `json:"users_processed"`
+	Percent        float64   `json:"percent"`
+	Upserted       int64     `json:"upserted"`
+	Invalidated    int64     `json:"invalidated"`
+	Cancelling     bool      `json:"cancelling"`
+	ElapsedMs      int64     `json:"elapsed_ms"`
+}
+
+func (p *MatchProgress) Snapshot() MatchProgressSnapshot {
+	s := MatchProgressSnapshot{
+		RunID:          p.RunID,
+		Workers:        p.Workers,
+		Shards:         p.Shards,
+		ShardsDone:     p.shardsDone.Load(),
+		TotalUsers:     p.TotalUsers,
+		UsersProcessed: p.usersProcessed.Load(),
+		Upserted:       p.upserted.Load(),
+		Invalidated:    p.invalidated.Load(),
+		Cancelling:     p.cancelling.Load(),
+		ElapsedMs:      time.Since(p.StartedAt).Milliseconds(),
+	}
+	if s.TotalUsers > 0 {
+		s.Percent = min(100, float64(s.UsersProcessed)*100/float64(s.TotalUsers))
+	}
+	return s
+}
+
+func (p *MatchProgress) addPage(users, upserted, invalidated int) {
+	p.usersProcessed.Add(int64(users))
+	p.upserted.Add(int64(upserted))
+	p.invalidated.Add(int64(invalidated))
+}
+
+type parallelRun struct {
+	progress *MatchProgress
+	cancel   context.CancelFunc
+}
+
+// parallelRuns holds the in-process parallel runs that are still executing.
+var parallelRuns sync.Map
+
+// MatchWorker matches the user shards it receives against the catalog, in the
+// same shape as UserWorker.
+func MatchWorker(ctx context.Context, id int, catalog *svc.ProductCatalog, shards <-chan svc.UserShard, results chan<- svc.ShardResult, progress *MatchProgress, wg *sync.WaitGroup) {
+	defer slog.Info("Match worker finished", "worker_id", id)
+	defer wg.Done()
+
+	for shard := range shards {
+		if ctx.Err() != nil {
+			results <- svc.ShardResult{Shard: shard.Index, Err: ctx.Err()}
+			continue
+		}
+		slog.Info("Match worker processing shard", "worker_id", id, "shard", shard.Index)
+		res := svc.MatchShard(ctx, catalog, shard, progress.addPage)
+		progress.shardsDone.Add(1)
+
+		if res.Err != nil {
+			slog.Error("Match worker shard failed", "worker_id", id, "shard", shard.Index, "error", res.Err)
+		} else {
+			slog.Info("Match worker shard completed", "worker_id", id, "shard", shard.Index, "users", res.Users, "upserted", res.Upserted, "invalidated", res.Invalidated)
+		}
+		results <- res
+	}
+}
+
+// StartParallelMatching records a parallel match run and returns once the
+// workers are started. The run continues in the background; its progress is
+// available from MatchRunProgress until it finishes.
+func StartParallelMatching(workers int) (*MatchProgress, error) {
+	if workers <= 0 {
+		workers = DefaultMatchWorkers
+	}
+	workers = min(workers, MaxMatchWorkers)
+
+	run, catalog, totalUsers, err := svc.StartParallelRun()
+	if err != nil {
+		return nil, err
+	}
+
+	shards := svc.UserShards(workers * shardsPerWorker)
+	progress := &MatchProgress{
+		RunID:      run.ID,
+		Workers:    workers,
+		Shards:     len(shards),
+		TotalUsers: totalUsers,
+		StartedAt:  time.Now(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	parallelRuns.Store(run.ID, &parallelRun{progress: progress, cancel: cancel})
+
+	jobs := make(chan svc.UserShard, len(shards))
+	results := make(chan svc.ShardResult, len(shards))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go MatchWorker(ctx, i, catalog, jobs, results, progress, &wg)
+	}
+	for _, shard := range shards {
+		jobs <- shard
+	}
+	close(jobs)
+
+	go func() {
+		defer parallelRuns.Delete(run.ID)
+		defer cancel()
+		wg.Wait()
+		close(results)
+
+		var upserted, invalidated int
+		var runErr error
+		for r := range results {
+			upserted += r.Upserted
+			invalidated += r.Invalidated
+			if r.Err != nil && runErr == nil {
+				runErr = r.Err
+			}
+		}
+		// A cancelled run reports as cancelled even if some shards failed first.
+		if ctx.Err() != nil {
+			runErr = ctx.Err()
+		}
+		svc.FinishParallelRun(run, catalog, upserted, invalidated, runErr)
+		slog.Info("Parallel match run finished", "run_id", run.ID, "upserted", upserted, "invalidated", invalidated, "error", runErr)
+	}()
+
+	slog.Info("Parallel match run started", "run_id", run.ID, "workers", workers, "shards", len(shards), "products", len(catalog.Products), "users", totalUsers)
+	return progress, nil
+}
+
+// MatchRunProgress returns the live progress of a parallel run executing in
+// this process.
+func MatchRunProgress(id uuid.UUID) (MatchProgressSnapshot, bool) {
+	v, ok := parallelRuns.Load(id)
+	if !ok {
+		return MatchProgressSnapshot{}, false
+	}
+	return v.(*parallelRun).progress.Snapshot(), true
+}
+
+// CancelMatchRun asks a running parallel run to stop. Workers finish the page
+// they are on, so matches already written stay consistent.
+func CancelMatchRun(id uuid.UUID) bool {
+	v, ok := parallelRuns.Load(id)
+	if !ok {
+		return false
+	}
+	r := v.(*parallelRun)
+	r.progress.cancelling.Store(true)
+	r.cancel()
+	return true
+}
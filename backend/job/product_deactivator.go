@@ -0,0 +1,27 @@
+package job
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/svc"
+)
+
+// DeactivateStaleProducts periodically deactivates products the crawler has
+// not reported within window, so they drop out of matching.
+func DeactivateStaleProducts(interval, window time.Duration) {
+	slog.Info("Product deactivator started", "interval", interval, "window", window)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		res, err := svc.DeactivateStaleProducts(window)
+		if err != nil {
+			slog.Error("Product deactivator failed", "error", err)
+			continue
+		}
+		if res.Products > 0 {
+			slog.Info("Product deactivator done", "products", res.Products, "matches_expired", res.MatchesExpired)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package job
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/svc"
+)
+
+// WatchProducts polls for products whose criteria changed since their last
+// match run and re-evaluates only those products.
+func WatchProducts(interval time.Duration) {
+	slog.Info("Product watcher started", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		rematchChangedProducts()
+		<-ticker.C
+	}
+}
+
+func rematchChangedProducts() {
+	if synced, err := svc.SyncProductRates(); err != nil {
+		slog.Error("Product watcher failed to sync interest rates", "error", err)
+	} else if synced > 0 {
+		slog.Info("Product watcher parsed interest rates", "count", synced)
+	}
+
+	products, err := svc.ProductsPendingRematch()
+	if err != nil {
+		slog.Error("Product watcher failed to load products", "error", err)
+		return
+	}
+	if len(products) == 0 {
+		return
+	}
+
+	// Changes made through the API are already versioned; this catches rows
+	// the crawler wrote directly.
+	if recorded, err := svc.RecordExternalProductChanges(products); err != nil {
+		slog.Error("Product watcher failed to record criteria history", "error", err)
+	} else if recorded > 0 {
+		slog.Info("Product watcher recorded criteria changes", "count", recorded)
+	}
+
+	slog.Info("Product watcher found changed products", "count", len(products))
+	for i := range products {
+		res, err := svc.RematchProductIfChanged(&products[i])
+		if err != nil {
+			slog.Error("Product watcher rematch failed", "product_id", products[i].ID, "error", err)
+			continue
+		}
+		slog.Info("Product watcher rematch done", "product_id", res.ProductID, "upserted", res.Upserted, "invalidated", res.Invalidated, "skipped", res.Skipped)
+	}
+}
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/google/uuid"
+)
+
+// ProductEntry is one product from a bulk payload, with its position so
+// results can be reported in request order.
+type ProductEntry struct {
+	Index int
+	Input svc.ProductInput
+}
+
+type ProductResult struct {
+	Index      int               `json:"index"`
+	ProductURL string            `json:"product_url"`
+	Status     svc.UpsertOutcome `json:"status"`
+	ProductID  *uuid.UUID        `json:"product_id,omitempty"`
+
+	// ProductStatus tells the crawler whether the product is held for review.
+	ProductStatus models.ProductStatus `json:"product_status,omitempty"`
+	Error         string               `json:"error,omitempty"`
+	Fields        map[string]string    `json:"fields,omitempty"`
+}
+
+// ProductWorker upserts batches of crawled products on ProductURL, in the
+// same shape as UserWorker.
+func ProductWorker(id int, jobs <-chan []ProductEntry, results chan<- []ProductResult, wg *sync.WaitGroup) {
+	defer slog.Info("Product worker finished", "worker_id", id)
+	defer wg.Done()
+
+	batchCount := 0
+	for batch := range jobs {
+		batchCount++
+		slog.Info("Product worker processing batch", "worker_id", id, "batch_num", batchCount, "batch_size", len(batch))
+
+		out := make([]ProductResult, 0, len(batch))
+		for _, entry := range batch {
+			out = append(out, upsertEntry(entry))
+		}
+		results <- out
+	}
+}
+
+func upsertEntry(entry ProductEntry) ProductResult {
+	res := ProductResult{Index: entry.Index, ProductURL: entry.Input.ProductURL}
+	product, outcome, err := svc.UpsertProductByURL(entry.Input, models.ProductSourceCrawler)
+	res.Status = outcome
+	if err != nil {
+		res.Status = svc.UpsertRejected
+		var invalid *svc.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			res.Error = "Invalid product"
+			res.Fields = invalid.Fields
+		case errors.Is(err, svc.ErrProductURLConflict):
+			res.Error = err.Error()
+		default:
+			slog.Error("Product worker upsert failed", "product_url", entry.Input.ProductURL, "error", err)
+			res.Error = "Failed to save product"
+		}
+		return res
+	}
+	res.ProductID = &product.ID
+	res.ProductStatus = product.Status
+	return res
+}
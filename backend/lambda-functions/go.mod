@@ -3,11 +3,11 @@ module github.com/BadadheVed/clickpe/lambda-functions
 go 1.24.5
 
 require (
+	github.com/BadadheVed/clickpe v0.0.0-00010101000000-000000000000
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/google/uuid v1.6.0
-	gorm.io/datatypes v1.2.7
-	gorm.io/driver/postgres v1.5.11
-	gorm.io/gorm v1.30.0
+	gorm.io/driver/postgres v1.6.0
+	gorm.io/gorm v1.31.1
 )
 
 require (
@@ -19,8 +19,15 @@ require (
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/stretchr/testify v1.11.1 // indirect
 	golang.org/x/crypto v0.45.0 // indirect
 	golang.org/x/sync v0.18.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
+	gorm.io/datatypes v1.2.7 // indirect
 	gorm.io/driver/mysql v1.5.6 // indirect
 )
+
+// The Lambda functions call into the backend module's svc, models and database
+// packages, so they pull in its gorm, Postgres and godotenv dependencies too.
+replace github.com/BadadheVed/clickpe => ../
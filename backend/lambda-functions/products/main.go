@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/BadadheVed/clickpe/lambda-functions/shared"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/svc"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func init() {
+	// Initialize database connection on cold start
+	if err := shared.InitDB(); err != nil {
+		panic(err)
+	}
+}
+
+func respond(status int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       `{"error": "Internal server error"}`,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+		}, err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Body:       string(payload),
+		Headers: map[string]string{
+			"Content-Type":                "application/json",
+			"Access-Control-Allow-Origin": "*",
+		},
+	}, nil
+}
+
+func errorBody(msg string) map[string]interface{} {
+	return map[string]interface{}{"error": msg}
+}
+
+// productError maps product errors to responses, as the backend controller does.
+func productError(op, failure string, err error) (events.APIGatewayProxyResponse, error) {
+	var invalid *svc.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		return respond(422, map[string]interface{}{"error": "Invalid product", "fields": invalid.Fields})
+	case errors.Is(err, svc.ErrProductURLConflict), errors.Is(err, svc.ErrProductNotInReview), errors.Is(err, svc.ErrProductMerged):
+		return respond(409, errorBody(err.Error()))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return respond(404, errorBody("Product not found"))
+	default:
+		slog.Error(op+": Failed", "error", err)
+		return respond(500, errorBody(failure))
+	}
+}
+
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	rawID, hasID := request.PathParameters["id"]
+	var id uuid.UUID
+	if hasID {
+		parsed, err := uuid.Parse(rawID)
+		if err != nil {
+			return respond(400, errorBody("Invalid product id"))
+		}
+		id = parsed
+	}
+
+	switch {
+	case !hasID && request.HTTPMethod == "GET":
+		limit := 0
+		if v := request.QueryStringParameters["limit"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > svc.MaxProductPageSize {
+				return respond(400, map[string]interface{}{"error": "Invalid query parameters", "fields": []string{"limit"}})
+			}
+			limit = n
+		}
+		page, err := svc.SearchProducts(svc.ProductSearch{Limit: limit, Cursor: request.QueryStringParameters["cursor"]})
+		if errors.Is(err, svc.ErrInvalidCursor) {
+			return respond(400, errorBody("Invalid cursor for this search"))
+		}
+		if err != nil {
+			slog.Error("ListProducts: Query failed", "error", err)
+			return respond(500, errorBody("Failed to load products"))
+		}
+		return respond(200, page)
+
+	case !hasID && request.HTTPMethod == "POST":
+		var in svc.ProductInput
+		if err := json.Unmarshal([]byte(request.Body), &in); err != nil {
+			return respond(400, errorBody("Invalid product body"))
+		}
+		product, err := svc.CreateProduct(in, models.ProductSourceAPI)
+		if err != nil {
+			return productError("CreateProduct", "Failed to create product", err)
+		}
+		return respond(201, product)
+
+	case hasID && request.HTTPMethod == "GET":
+		product, err := svc.GetProduct(id)
+		if err != nil {
+			return productError("GetProduct", "Failed to load product", err)
+		}
+		return respond(200, product)
+
+	case hasID && request.HTTPMethod == "PUT":
+		var in svc.ProductInput
+		if err := json.Unmarshal([]byte(request.Body), &in); err != nil {
+			return respond(400, errorBody("Invalid product body"))
+		}
+		product, err := svc.UpdateProduct(id, in, models.ProductSourceAPI)
+		if err != nil {
+			return productError("UpdateProduct", "Failed to update product", err)
+		}
+		return respond(200, product)
+
+	case hasID && request.HTTPMethod == "DELETE":
+		if err := svc.DeleteProduct(id); err != nil {
+			return productError("DeleteProduct", "Failed to delete product", err)
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: 204,
+			Headers:    map[string]string{"Access-Control-Allow-Origin": "*"},
+		}, nil
+	}
+
+	return respond(405, errorBody("Method not allowed"))
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -4,13 +4,13 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/BadadheVed/clickpe/database"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-// InitDB initializes the database connection using DATABASE_URL env variable
+// InitDB connects database.DB, which the backend packages the functions call
+// into use, with the DATABASE_URL env variable.
 func InitDB() error {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
@@ -18,15 +18,14 @@ func InitDB() error {
 		return &DBError{Message: "DATABASE_URL is required"}
 	}
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		slog.Error("Failed to connect to database", "error", err)
 		return err
 	}
 
 	// Set connection pool settings
-	sqlDB, err := DB.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
 		slog.Error("Failed to get database instance", "error", err)
 		return err
@@ -35,16 +34,13 @@ func InitDB() error {
 	sqlDB.SetMaxOpenConns(20)
 	sqlDB.SetMaxIdleConns(10)
 
-	// Auto-migrate all models
-	err = DB.AutoMigrate(
-		&User{},
-		&LoanProduct{},
-		&Match{},
-	)
-	if err != nil {
-		slog.Error("Failed to auto-migrate tables", "error", err)
+	// Migrate with the backend's code, which dedupes matches before the
+	// unique match index is created.
+	if err := database.Migrate(db); err != nil {
+		slog.Error("Failed to migrate database", "error", err)
 		return err
 	}
+	database.DB = db
 
 	slog.Info("Database connected successfully")
 	return nil
@@ -14,7 +14,9 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/BadadheVed/clickpe/database"
 	"github.com/BadadheVed/clickpe/lambda-functions/shared"
+	"github.com/BadadheVed/clickpe/models"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/google/uuid"
@@ -27,9 +29,15 @@ func init() {
 	}
 }
 
-func saveUsersBatch(batch []shared.User) (int, error) {
+// BatchResult is what a worker reports for one batch.
+type BatchResult struct {
+	Inserted  int
+	Attempted int
+}
+
+func saveUsersBatch(batch []models.User) (int, error) {
 	slog.Info("SaveUsersBatch: Starting insert", "batch_size", len(batch))
-	result := shared.DB.Create(&batch)
+	result := database.DB.Create(&batch)
 
 	if result.Error != nil {
 		slog.Error("SaveUsersBatch: Insert failed", "error", result.Error, "batch_size", len(batch))
@@ -40,7 +48,7 @@ func saveUsersBatch(batch []shared.User) (int, error) {
 	return int(result.RowsAffected), nil
 }
 
-func userWorker(id int, jobs <-chan []shared.User, results chan<- shared.BatchResult, wg *sync.WaitGroup) {
+func userWorker(id int, jobs <-chan []models.User, results chan<- BatchResult, wg *sync.WaitGroup) {
 	defer slog.Info("Worker finished", "worker_id", id)
 	defer wg.Done()
 
@@ -52,10 +60,10 @@ func userWorker(id int, jobs <-chan []shared.User, results chan<- shared.BatchRe
 
 		if err != nil {
 			slog.Error("Worker batch failed", "worker_id", id, "batch_num", batchCount, "error", err)
-			results <- shared.BatchResult{Inserted: 0, Attempted: len(batch)}
+			results <- BatchResult{Inserted: 0, Attempted: len(batch)}
 		} else {
 			slog.Info("Worker batch completed", "worker_id", id, "batch_num", batchCount, "inserted", inserted, "attempted", len(batch))
-			results <- shared.BatchResult{Inserted: inserted, Attempted: len(batch)}
+			results <- BatchResult{Inserted: inserted, Attempted: len(batch)}
 		}
 	}
 }
@@ -71,8 +79,8 @@ func processCSV(fileContent []byte) (map[string]interface{}, error) {
 
 	const workerCount = 5
 	const channelBufferSize = 100
-	jobs := make(chan []shared.User, channelBufferSize)
-	results := make(chan shared.BatchResult, channelBufferSize)
+	jobs := make(chan []models.User, channelBufferSize)
+	results := make(chan BatchResult, channelBufferSize)
 	var wg sync.WaitGroup
 
 	for i := 0; i < workerCount; i++ {
@@ -83,7 +91,7 @@ func processCSV(fileContent []byte) (map[string]interface{}, error) {
 
 	var (
 		batchSize      = 100
-		batch          []shared.User
+		batch          []models.User
 		addedCount     int
 		failedCount    int
 		skippedCount   int
@@ -127,14 +135,33 @@ func processCSV(fileContent []byte) (map[string]interface{}, error) {
 		age, _ := strconv.Atoi(row[6])
 		creditScore, _ := strconv.Atoi(row[4])
 		income, _ := strconv.ParseFloat(row[3], 64)
+		// Affordability and location columns are optional, as in the backend import.
+		obligations, _ := strconv.ParseFloat(optionalColumn(row, 7), 64)
+		requestedAmount, _ := strconv.ParseFloat(optionalColumn(row, 8), 64)
+		requestedTenure, _ := strconv.Atoi(optionalColumn(row, 9))
+		cityTier, _ := strconv.Atoi(optionalColumn(row, 12))
+		loanType := models.LoanType(strings.ToLower(optionalColumn(row, 13)))
+		if !models.ValidLoanType(loanType) {
+			loanType = ""
+		}
 
-		user := shared.User{
-			ID:            id,
-			Name:          row[1],
-			Email:         row[2],
-			Age:           age,
-			CreditScore:   creditScore,
-			MonthlyIncome: income,
+		user := models.User{
+			ID:               id,
+			Name:             row[1],
+			Email:            row[2],
+			Age:              age,
+			CreditScore:      creditScore,
+			MonthlyIncome:    income,
+			EmploymentStatus: row[5],
+
+			MonthlyObligations:    obligations,
+			RequestedAmount:       requestedAmount,
+			RequestedTenureMonths: requestedTenure,
+			RequestedLoanType:     loanType,
+
+			Pincode:  optionalColumn(row, 10),
+			City:     optionalColumn(row, 11),
+			CityTier: cityTier,
 		}
 
 		batch = append(batch, user)
@@ -143,7 +170,7 @@ func processCSV(fileContent []byte) (map[string]interface{}, error) {
 			batchesSent++
 			slog.Info("Sending batch to jobs channel", "batch_num", batchesSent, "batch_size", len(batch), "total_rows_read", totalRowsRead)
 			jobs <- batch
-			batch = []shared.User{}
+			batch = []models.User{}
 		}
 	}
 
@@ -180,6 +207,13 @@ func processCSV(fileContent []byte) (map[string]interface{}, error) {
 	}, nil
 }
 
+func optionalColumn(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Parse multipart form data
 	contentType := request.Headers["content-type"]
@@ -2,15 +2,40 @@ package main
 
 import (
 	"log/slog"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/job"
 	"github.com/BadadheVed/clickpe/router"
+	"github.com/BadadheVed/clickpe/svc"
+)
+
+const (
+	defaultProductWatchInterval = time.Minute
+	defaultMatchSweepInterval   = time.Hour
+	defaultStaleCheckInterval   = time.Hour
+	defaultBankMergeInterval    = 24 * time.Hour
 )
 
 func main() {
 
 	database.DBConnect()
 	slog.Info("Databae Connected")
+
+	svc.MatchValidity = envDuration("MATCH_VALIDITY", svc.MatchValidity)
+	svc.FairnessThreshold = envFloat("FAIRNESS_DISPARITY_THRESHOLD", svc.FairnessThreshold)
+	svc.ProductReviewTolerance = envFloat("PRODUCT_REVIEW_TOLERANCE", svc.ProductReviewTolerance)
+	go job.WatchProducts(envDuration("PRODUCT_WATCH_INTERVAL", defaultProductWatchInterval))
+	go job.SweepMatches(envDuration("MATCH_SWEEP_INTERVAL", defaultMatchSweepInterval))
+	go job.DeactivateStaleProducts(
+		envDuration("PRODUCT_STALE_CHECK_INTERVAL", defaultStaleCheckInterval),
+		envDuration("PRODUCT_STALE_AFTER", svc.ProductStaleAfter))
+	// Match free-text bank names against banks and queue the rest as merge
+	// proposals for an admin to confirm.
+	go job.ProposeBankMerges(envDuration("BANK_MERGE_INTERVAL", defaultBankMergeInterval))
+
 	r := router.SetupRouter()
 
 	slog.Info("Router Initialized")
@@ -22,3 +47,29 @@ func main() {
 	}
 	slog.Info("Server started on port 8080")
 }
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		slog.Warn("Invalid duration in env, using default", "key", key, "value", v, "default", fallback)
+		return fallback
+	}
+	return d
+}
+
+func envFloat(key string, fallback float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		slog.Warn("Invalid number in env, using default", "key", key, "value", v, "default", fallback)
+		return fallback
+	}
+	return f
+}
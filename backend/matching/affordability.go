@@ -0,0 +1,81 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/BadadheVed/clickpe/models"
+)
+
+// Affordability is the EMI and fixed-obligation-to-income ratio (FOIR) a
+// borrower would carry if the requested loan were disbursed on this product.
+type Affordability struct {
+	RequestedAmount     float64 `json:"requested_amount"`
+	TenureMonths        int     `json:"tenure_months"`
+	APR                 float64 `json:"apr"`
+	EMI                 float64 `json:"emi"`
+	ExistingObligations float64 `json:"existing_obligations"`
+	FOIR                float64 `json:"foir"`
+	MaxFOIR             float64 `json:"max_foir"`
+}
+
+// EMI is the standard reducing-balance instalment for principal at apr
+// percent per year over months.
+func EMI(principal, apr float64, months int) float64 {
+	if principal <= 0 || months <= 0 {
+		return 0
+	}
+	r := apr / 12 / 100
+	if r == 0 {
+		return round2(principal / float64(months))
+	}
+	f := math.Pow(1+r, float64(months))
+	return round2(principal * r * f / (f - 1))
+}
+
+// AssessAffordability prices the requested loan at the product's highest
+// advertised APR, so the check stays conservative for range-priced products.
+// Without a requested loan only the existing obligations count towards FOIR.
+func AssessAffordability(u *models.User, p *models.LoanProduct, maxFOIR float64) (*Affordability, Check) {
+	a := &Affordability{
+		RequestedAmount:     u.RequestedAmount,
+		TenureMonths:        u.RequestedTenureMonths,
+		ExistingObligations: u.MonthlyObligations,
+		MaxFOIR:             maxFOIR,
+	}
+	check := Check{Criterion: "foir"}
+
+	if u.RequestedAmount > 0 && u.RequestedTenureMonths > 0 {
+		apr, ok := conservativeAPR(p)
+		if !ok {
+			check.Detail = fmt.Sprintf("cannot compute EMI: interest rate %q not parsed", p.InterestRate)
+			return a, check
+		}
+		a.APR = apr
+		a.EMI = EMI(u.RequestedAmount, apr, u.RequestedTenureMonths)
+	}
+
+	if u.MonthlyIncome <= 0 {
+		check.Detail = "cannot compute FOIR without monthly income"
+		return a, check
+	}
+	a.FOIR = round2((a.ExistingObligations + a.EMI) / u.MonthlyIncome)
+	check.Passed = a.FOIR <= maxFOIR
+	check.Gap = shortfall(a.FOIR, maxFOIR)
+	check.Detail = fmt.Sprintf("FOIR %.2f (EMI %.2f + obligations %.2f) vs maximum %.2f", a.FOIR, a.EMI, a.ExistingObligations, maxFOIR)
+	return a, check
+}
+
+func conservativeAPR(p *models.LoanProduct) (float64, bool) {
+	if p.MaxAPR != nil {
+		return *p.MaxAPR, true
+	}
+	if p.MinAPR != nil {
+		return *p.MinAPR, true
+	}
+	return 0, false
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
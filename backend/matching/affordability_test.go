@@ -0,0 +1,113 @@
+package matching_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+)
+
+func TestEMI(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal float64
+		apr       float64
+		months    int
+		want      float64
+	}{
+		{"one year at 12%", 100000, 12, 12, 8884.88},
+		{"three years at 24%", 500000, 24, 36, 19616.43},
+		{"twenty years at 8.5%", 1000000, 8.5, 240, 8678.23},
+		{"interest free", 120000, 0, 12, 10000},
+		{"no principal", 0, 12, 12, 0},
+		{"no tenure", 100000, 12, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matching.EMI(tt.principal, tt.apr, tt.months); got != tt.want {
+				t.Errorf("EMI(%v, %v, %d) = %v, want %v", tt.principal, tt.apr, tt.months, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssessAffordability(t *testing.T) {
+	borrower := func(obligations, amount float64, tenure int) *models.User {
+		return matchingtest.User(func(u *models.User) {
+			u.MonthlyObligations, u.RequestedAmount, u.RequestedTenureMonths = obligations, amount, tenure
+		})
+	}
+	withRate := func(rate string) *models.LoanProduct {
+		return matchingtest.Product(func(p *models.LoanProduct) {
+			p.InterestRate = rate
+			p.ParseInterestRate()
+		})
+	}
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		product *models.LoanProduct
+		maxFOIR float64
+		pass    bool
+		apr     float64
+		emi     float64
+		foir    float64
+		gap     float64
+		detail  string
+	}{
+		{name: "obligations only", user: borrower(10000, 0, 0), maxFOIR: 0.5, pass: true, foir: 0.2},
+		{name: "amount without tenure", user: borrower(10000, 300000, 0), maxFOIR: 0.5, pass: true, foir: 0.2},
+		{name: "priced at the highest APR", user: borrower(10000, 300000, 24), maxFOIR: 0.6, pass: true, apr: 24, emi: 15861.33, foir: 0.52},
+		{name: "over the limit", user: borrower(10000, 300000, 24), maxFOIR: 0.5, apr: 24, emi: 15861.33, foir: 0.52, gap: 0.02},
+		{name: "at the limit", user: borrower(26000, 0, 0), maxFOIR: 0.52, pass: true, foir: 0.52},
+		{name: "single rate", user: borrower(0, 100000, 12), product: withRate("12% p.a."), maxFOIR: 0.5, pass: true, apr: 12, emi: 8884.88, foir: 0.18},
+		{name: "rate not parsed", user: borrower(0, 100000, 12), product: withRate("contact branch"), maxFOIR: 0.5,
+			detail: `cannot compute EMI: interest rate "contact branch" not parsed`},
+		{name: "no income", user: matchingtest.User(func(u *models.User) { u.MonthlyIncome = 0 }), maxFOIR: 0.5,
+			detail: "cannot compute FOIR without monthly income"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := tt.product
+			if product == nil {
+				product = matchingtest.Product()
+			}
+			aff, check := matching.AssessAffordability(tt.user, product, tt.maxFOIR)
+			if check.Criterion != "foir" || check.Passed != tt.pass || check.Gap != tt.gap {
+				t.Errorf("check = %+v, want passed %t with gap %v", check, tt.pass, tt.gap)
+			}
+			if tt.detail != "" && check.Detail != tt.detail {
+				t.Errorf("detail = %q, want %q", check.Detail, tt.detail)
+			}
+			if aff.APR != tt.apr || aff.EMI != tt.emi || aff.FOIR != tt.foir || aff.MaxFOIR != tt.maxFOIR {
+				t.Errorf("affordability = %+v, want APR %v, EMI %v, FOIR %v", *aff, tt.apr, tt.emi, tt.foir)
+			}
+		})
+	}
+}
+
+func TestEvaluateChecksAffordability(t *testing.T) {
+	user := matchingtest.User(func(u *models.User) {
+		u.MonthlyObligations, u.RequestedAmount, u.RequestedTenureMonths = 10000, 300000, 24
+	})
+	withFOIR := func(max float64) *models.LoanProduct {
+		return matchingtest.Product(func(p *models.LoanProduct) { p.MaxFOIR = max })
+	}
+
+	matchingtest.AssertEligible(t, user, withFOIR(0.6), true)
+	matchingtest.AssertEligible(t, user, withFOIR(0.5), false)
+
+	res := matching.Evaluate(user, withFOIR(0.5))
+	if res.Affordability == nil || res.Affordability.EMI != 15861.33 {
+		t.Errorf("Evaluate affordability = %+v, want the EMI of the requested loan", res.Affordability)
+	}
+	if !strings.HasPrefix(res.FailureReason(), "FOIR 0.52") {
+		t.Errorf("Evaluate failure reason = %q, want the FOIR check", res.FailureReason())
+	}
+	if res := matching.Evaluate(user, matchingtest.Product()); res.Affordability != nil {
+		t.Errorf("Evaluate without a FOIR limit assessed affordability: %+v", res.Affordability)
+	}
+}
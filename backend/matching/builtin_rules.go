@@ -0,0 +1,77 @@
+package matching
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+func init() {
+	Register(NewRule("max_age", maxAge))
+	Register(NewRule("employment_status", employmentStatus))
+	Register(NewRule("employment_status_salaried", employmentStatusSalaried))
+	Register(NewRule("foir", foir))
+}
+
+type maxAgeParams struct {
+	Max int `json:"max"`
+}
+
+func maxAge(ctx *Context) Check {
+	var params maxAgeParams
+	if err := ctx.DecodeParams(&params); err != nil {
+		return Check{Passed: false, Detail: "invalid max_age params: " + err.Error()}
+	}
+	check := Check{
+		Passed: params.Max <= 0 || ctx.User.Age <= params.Max,
+		Detail: fmt.Sprintf("age %d vs maximum %d", ctx.User.Age, params.Max),
+	}
+	if !check.Passed {
+		check.Gap = shortfall(float64(ctx.User.Age), float64(params.Max))
+	}
+	return check
+}
+
+type employmentStatusParams struct {
+	Allowed []string `json:"allowed"`
+}
+
+func employmentStatus(ctx *Context) Check {
+	var params employmentStatusParams
+	if err := ctx.DecodeParams(&params); err != nil {
+		return Check{Passed: false, Detail: "invalid employment_status params: " + err.Error()}
+	}
+	return Check{
+		Passed: len(params.Allowed) == 0 || slices.ContainsFunc(params.Allowed, func(s string) bool {
+			return strings.EqualFold(s, ctx.User.EmploymentStatus)
+		}),
+		Detail: fmt.Sprintf("employment status %q vs allowed %v", ctx.User.EmploymentStatus, params.Allowed),
+	}
+}
+
+// employmentStatusSalaried only checks the employment status; the employer is
+// not known, so rules such as "salaried at a listed company" need their own rule.
+func employmentStatusSalaried(ctx *Context) Check {
+	return Check{
+		Passed: strings.EqualFold(ctx.User.EmploymentStatus, "salaried"),
+		Detail: fmt.Sprintf("employment status %q vs required salaried", ctx.User.EmploymentStatus),
+	}
+}
+
+type foirParams struct {
+	Max float64 `json:"max"`
+}
+
+// foir lets a product apply an affordability limit from RawCriteria, e.g.
+// {"name": "foir", "params": {"max": 0.5}}.
+func foir(ctx *Context) Check {
+	var params foirParams
+	if err := ctx.DecodeParams(&params); err != nil {
+		return Check{Passed: false, Detail: "invalid foir params: " + err.Error()}
+	}
+	if params.Max <= 0 {
+		return Check{Passed: false, Detail: "foir rule requires params.max"}
+	}
+	_, check := AssessAffordability(ctx.User, ctx.Product, params.Max)
+	return check
+}
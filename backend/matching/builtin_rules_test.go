@@ -0,0 +1,168 @@
+package matching_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+)
+
+func lookup(t *testing.T, name string) matching.Rule {
+	t.Helper()
+	rule, ok := matching.LookupRule(name)
+	if !ok {
+		t.Fatalf("rule %q is not registered", name)
+	}
+	return rule
+}
+
+func TestBuiltinRules(t *testing.T) {
+	withStatus := func(status string) func(*models.User) {
+		return func(u *models.User) { u.EmploymentStatus = status }
+	}
+	tests := []struct {
+		name    string
+		rule    string
+		user    *models.User
+		product *models.LoanProduct
+		params  any
+		pass    bool
+		detail  string
+	}{
+		{name: "max_age below", rule: "max_age", params: map[string]any{"max": 60}, pass: true},
+		{name: "max_age at limit", rule: "max_age", user: matchingtest.User(func(u *models.User) { u.Age = 60 }), params: map[string]any{"max": 60}, pass: true},
+		{name: "max_age above", rule: "max_age", user: matchingtest.User(func(u *models.User) { u.Age = 61 }), params: map[string]any{"max": 60}},
+		{name: "max_age without max", rule: "max_age", user: matchingtest.User(func(u *models.User) { u.Age = 99 }), pass: true},
+		{name: "max_age invalid params", rule: "max_age", params: map[string]any{"max": "sixty"}, detail: "invalid max_age params"},
+
+		{name: "employment_status allowed", rule: "employment_status", params: map[string]any{"allowed": []string{"salaried", "self_employed"}}, pass: true},
+		{name: "employment_status case-insensitive", rule: "employment_status", user: matchingtest.User(withStatus("Salaried")), params: map[string]any{"allowed": []string{"SALARIED"}}, pass: true},
+		{name: "employment_status not allowed", rule: "employment_status", user: matchingtest.User(withStatus("student")), params: map[string]any{"allowed": []string{"salaried"}}},
+		{name: "employment_status empty list", rule: "employment_status", user: matchingtest.User(withStatus("")), params: map[string]any{"allowed": []string{}}, pass: true},
+		{name: "employment_status invalid params", rule: "employment_status", params: map[string]any{"allowed": "salaried"}, detail: "invalid employment_status params"},
+
+		{name: "salaried", rule: "employment_status_salaried", pass: true},
+		{name: "salaried case-insensitive", rule: "employment_status_salaried", user: matchingtest.User(withStatus("SALARIED")), pass: true},
+		{name: "salaried self-employed", rule: "employment_status_salaried", user: matchingtest.User(withStatus("self_employed"))},
+		{name: "salaried unknown", rule: "employment_status_salaried", user: matchingtest.User(withStatus(""))},
+
+		{name: "foir obligations only", rule: "foir", user: matchingtest.User(func(u *models.User) { u.MonthlyObligations = 20000 }), params: map[string]any{"max": 0.5}, pass: true},
+		{name: "foir obligations over max", rule: "foir", user: matchingtest.User(func(u *models.User) { u.MonthlyObligations = 20000 }), params: map[string]any{"max": 0.3}},
+		{
+			name: "foir requested loan over max", rule: "foir",
+			user: matchingtest.User(func(u *models.User) {
+				u.MonthlyObligations, u.RequestedAmount, u.RequestedTenureMonths = 20000, 500000, 36
+			}),
+			params: map[string]any{"max": 0.5},
+		},
+		{
+			name: "foir unparsed rate", rule: "foir",
+			user: matchingtest.User(func(u *models.User) { u.RequestedAmount, u.RequestedTenureMonths = 100000, 12 }),
+			product: matchingtest.Product(func(p *models.LoanProduct) {
+				p.InterestRate = "contact branch"
+				p.ParseInterestRate()
+			}),
+			params: map[string]any{"max": 0.5},
+			detail: "cannot compute EMI",
+		},
+		{name: "foir without income", rule: "foir", user: matchingtest.User(func(u *models.User) { u.MonthlyIncome = 0 }), params: map[string]any{"max": 0.5}, detail: "without monthly income"},
+		{name: "foir without max", rule: "foir", detail: "requires params.max"},
+		{name: "foir invalid params", rule: "foir", params: map[string]any{"max": "half"}, detail: "invalid foir params"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, product := tt.user, tt.product
+			if user == nil {
+				user = matchingtest.User()
+			}
+			if product == nil {
+				product = matchingtest.Product()
+			}
+			rule := lookup(t, tt.rule)
+			if tt.pass {
+				matchingtest.AssertPasses(t, rule, user, product, tt.params)
+			} else {
+				matchingtest.AssertFails(t, rule, user, product, tt.params)
+			}
+			if check := matchingtest.Eval(rule, user, product, tt.params); !strings.Contains(check.Detail, tt.detail) {
+				t.Errorf("detail %q does not mention %q", check.Detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestBuiltinRulesFromRawCriteria(t *testing.T) {
+	product := matchingtest.Product(matchingtest.WithRules(
+		matchingtest.Ref("employment_status_salaried", nil),
+		matchingtest.Ref("max_age", map[string]any{"max": 55}),
+	))
+	matchingtest.AssertEligible(t, matchingtest.User(), product, true)
+	matchingtest.AssertEligible(t, matchingtest.User(func(u *models.User) { u.Age = 56 }), product, false)
+	matchingtest.AssertEligible(t, matchingtest.User(func(u *models.User) { u.EmploymentStatus = "self_employed" }), product, false)
+}
+
+func TestUnknownRule(t *testing.T) {
+	if _, ok := matching.LookupRule("no_such_rule"); ok {
+		t.Fatal("LookupRule found an unregistered rule")
+	}
+
+	check := matching.EvaluateRule(matching.RuleRef{Name: "no_such_rule"}, matchingtest.User(), matchingtest.Product())
+	if check.Passed || check.Criterion != "no_such_rule" || !strings.Contains(check.Detail, "unknown rule") {
+		t.Errorf("unknown rule check = %+v, want a failed no_such_rule check", check)
+	}
+
+	// A typo in the criteria must never widen eligibility.
+	product := matchingtest.Product(matchingtest.WithRules(matchingtest.Ref("salaired", nil)))
+	matchingtest.AssertEligible(t, matchingtest.User(), product, false)
+}
+
+func TestValidateRawCriteria(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		err  string
+	}{
+		{name: "empty", raw: ""},
+		{name: "no rules", raw: `{}`},
+		{name: "known rules", raw: `{"rules": ["employment_status_salaried", {"name": "foir", "params": {"max": 0.5}}]}`},
+		{name: "not an object", raw: `["salaried"]`, err: "must be a JSON object"},
+		{name: "malformed rule", raw: `{"rules": [42]}`, err: "invalid raw_criteria"},
+		{name: "unknown rule", raw: `{"rules": ["salaired"]}`, err: `unknown rule "salaired"; known rules: `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := matching.ValidateRawCriteria([]byte(tt.raw))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsBadNames(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"empty name", ""},
+		{"duplicate name", "employment_status_salaried"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Register did not panic")
+				}
+			}()
+			matching.Register(matching.NewRule(tt.rule, func(*matching.Context) matching.Check {
+				return matching.Check{Passed: true}
+			}))
+		})
+	}
+}
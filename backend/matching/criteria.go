@@ -0,0 +1,129 @@
+package matching
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BadadheVed/clickpe/models"
+)
+
+// Criteria holds the residual rules from LoanProduct.RawCriteria that are not
+// covered by the structured columns. Unknown keys are ignored.
+type Criteria struct {
+	MaxAge           int       `json:"max_age,omitempty"`
+	EmploymentStatus []string  `json:"employment_status,omitempty"`
+	Rules            []RuleRef `json:"rules,omitempty"`
+
+	// IncomeMultiplier overrides DefaultIncomeMultiplier for loan estimates.
+	IncomeMultiplier float64 `json:"income_multiplier,omitempty"`
+}
+
+// RuleRefs returns every residual rule to run, with the max_age and
+// employment_status shorthands expanded to their registered rules.
+func (c Criteria) RuleRefs() []RuleRef {
+	var refs []RuleRef
+	if c.MaxAge > 0 {
+		params, _ := json.Marshal(maxAgeParams{Max: c.MaxAge})
+		refs = append(refs, RuleRef{Name: "max_age", Params: params})
+	}
+	if len(c.EmploymentStatus) > 0 {
+		params, _ := json.Marshal(employmentStatusParams{Allowed: c.EmploymentStatus})
+		refs = append(refs, RuleRef{Name: "employment_status", Params: params})
+	}
+	return append(refs, c.Rules...)
+}
+
+func ParseCriteria(p *models.LoanProduct) (Criteria, error) {
+	var c Criteria
+	if len(p.RawCriteria) == 0 || string(p.RawCriteria) == "null" {
+		return c, nil
+	}
+	if err := json.Unmarshal(p.RawCriteria, &c); err != nil {
+		return c, fmt.Errorf("invalid raw_criteria for product %s: %w", p.ID, err)
+	}
+	return c, nil
+}
+
+// ValidateRawCriteria checks raw criteria before a product is saved: they must
+// be a JSON object of residual criteria naming only registered rules. Both the
+// backend and the Lambda functions validate with it.
+func ValidateRawCriteria(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return errors.New("must be a JSON object")
+	}
+	var c Criteria
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return fmt.Errorf("invalid raw_criteria: %w", err)
+	}
+	for _, ref := range c.RuleRefs() {
+		if _, ok := LookupRule(ref.Name); !ok {
+			return fmt.Errorf("unknown rule %q; known rules: %s", ref.Name, strings.Join(RuleNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// CriteriaSnapshot captures every product field that affects eligibility or
+// the loan estimate, as the engine saw it.
+type CriteriaSnapshot struct {
+	MinCreditScore   int      `json:"min_credit_score"`
+	MinMonthlyIncome float64  `json:"min_monthly_income"`
+	Age              int      `json:"age"`
+	MaxFOIR          float64  `json:"max_foir"`
+	InterestRate     string   `json:"interest_rate"`
+	MinAPR           *float64 `json:"min_apr"`
+	MaxAPR           *float64 `json:"max_apr"`
+	MinLoanAmount    float64  `json:"min_loan_amount"`
+	MaxLoanAmount    float64  `json:"max_loan_amount"`
+	MaxTenureMonths  int      `json:"max_tenure_months"`
+
+	LoanType        models.LoanType `json:"loan_type,omitempty"`
+	MinTenureMonths int             `json:"min_tenure_months,omitempty"`
+
+	ServiceablePincodes []string `json:"serviceable_pincodes,omitempty"`
+	ServiceableCities   []string `json:"serviceable_cities,omitempty"`
+	CityTiers           []int    `json:"city_tiers,omitempty"`
+
+	Criteria Criteria `json:"criteria"`
+}
+
+func Snapshot(p *models.LoanProduct) CriteriaSnapshot {
+	c, _ := ParseCriteria(p)
+	return CriteriaSnapshot{
+		MinCreditScore:   p.MinCreditScore,
+		MinMonthlyIncome: p.MinMonthlyIncome,
+		Age:              p.Age,
+		MaxFOIR:          p.MaxFOIR,
+		InterestRate:     p.InterestRate,
+		MinAPR:           p.MinAPR,
+		MaxAPR:           p.MaxAPR,
+		MinLoanAmount:    p.MinLoanAmount,
+		MaxLoanAmount:    p.MaxLoanAmount,
+		MaxTenureMonths:  p.MaxTenureMonths,
+
+		LoanType:        p.LoanType,
+		MinTenureMonths: p.MinTenureMonths,
+
+		ServiceablePincodes: p.ServiceablePincodes,
+		ServiceableCities:   p.ServiceableCities,
+		CityTiers:           p.CityTiers,
+
+		Criteria: c,
+	}
+}
+
+// CriteriaHash fingerprints the criteria snapshot, so a change to the product
+// name or URL does not trigger a rematch.
+func CriteriaHash(p *models.LoanProduct) string {
+	payload, _ := json.Marshal(Snapshot(p))
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package matching
+
+import (
+	"math"
+
+	"github.com/BadadheVed/clickpe/models"
+)
+
+const (
+	DefaultTenureMonths     = 60
+	DefaultIncomeMultiplier = 15
+	EstimateRounding        = 1000
+)
+
+type EstimateBasis string
+
+const (
+	EstimateFOIRHeadroom     EstimateBasis = "foir_headroom"
+	EstimateIncomeMultiplier EstimateBasis = "income_multiplier"
+)
+
+// LoanEstimate answers "how much can I get" for an eligible match.
+type LoanEstimate struct {
+	MaxAmount    float64       `json:"max_amount"`
+	TenureMonths int           `json:"tenure_months"`
+	Basis        EstimateBasis `json:"basis"`
+	BelowMinimum bool          `json:"below_minimum"`
+}
+
+// EstimateLoan sizes the largest loan the borrower can take on the product.
+// Products with a FOIR limit and a parsed rate use the EMI headroom left under
+// that limit; all others use a multiple of monthly income. The result is
+// capped by the product's amount range and rounded down.
+func EstimateLoan(u *models.User, p *models.LoanProduct) *LoanEstimate {
+	e := &LoanEstimate{TenureMonths: p.MaxTenureMonths}
+	if e.TenureMonths <= 0 {
+		e.TenureMonths = DefaultTenureMonths
+	}
+	if u.RequestedTenureMonths > 0 && u.RequestedTenureMonths < e.TenureMonths {
+		e.TenureMonths = u.RequestedTenureMonths
+	}
+	if p.MinTenureMonths > e.TenureMonths {
+		e.TenureMonths = p.MinTenureMonths
+	}
+
+	amount := 0.0
+	if apr, ok := conservativeAPR(p); ok && p.MaxFOIR > 0 {
+		e.Basis = EstimateFOIRHeadroom
+		headroom := p.MaxFOIR*u.MonthlyIncome - u.MonthlyObligations
+		amount = principalFor(headroom, apr, e.TenureMonths)
+	} else {
+		e.Basis = EstimateIncomeMultiplier
+		amount = u.MonthlyIncome * incomeMultiplier(p)
+	}
+
+	if p.MaxLoanAmount > 0 {
+		amount = math.Min(amount, p.MaxLoanAmount)
+	}
+	amount = math.Max(0, math.Floor(amount/EstimateRounding)*EstimateRounding)
+	if p.MinLoanAmount > 0 && amount < p.MinLoanAmount {
+		amount = 0
+		e.BelowMinimum = true
+	}
+	e.MaxAmount = amount
+	return e
+}
+
+// principalFor inverts EMI: the principal an instalment of emi repays.
+func principalFor(emi, apr float64, months int) float64 {
+	if emi <= 0 || months <= 0 {
+		return 0
+	}
+	r := apr / 12 / 100
+	if r == 0 {
+		return emi * float64(months)
+	}
+	f := math.Pow(1+r, float64(months))
+	return emi * (f - 1) / (r * f)
+}
+
+func incomeMultiplier(p *models.LoanProduct) float64 {
+	c, err := ParseCriteria(p)
+	if err != nil || c.IncomeMultiplier <= 0 {
+		return DefaultIncomeMultiplier
+	}
+	return c.IncomeMultiplier
+}
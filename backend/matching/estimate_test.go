@@ -0,0 +1,75 @@
+package matching_test
+
+import (
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+)
+
+func TestEstimateLoan(t *testing.T) {
+	user := func(opts ...func(*models.User)) *models.User { return matchingtest.User(opts...) }
+	product := func(opts ...func(*models.LoanProduct)) *models.LoanProduct { return matchingtest.Product(opts...) }
+	withFOIR := func(p *models.LoanProduct) { p.MaxFOIR = 0.5 }
+	obligations := func(v float64) func(*models.User) {
+		return func(u *models.User) { u.MonthlyObligations = v }
+	}
+	tenures := func(min, max int) func(*models.LoanProduct) {
+		return func(p *models.LoanProduct) { p.MinTenureMonths, p.MaxTenureMonths = min, max }
+	}
+	requestedTenure := func(months int) func(*models.User) {
+		return func(u *models.User) { u.RequestedTenureMonths = months }
+	}
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		product *models.LoanProduct
+		want    matching.LoanEstimate
+	}{
+		{"default multiplier", user(), product(),
+			matching.LoanEstimate{MaxAmount: 750000, TenureMonths: 60, Basis: matching.EstimateIncomeMultiplier}},
+		{"product multiplier", user(), product(matchingtest.WithRawCriteria(map[string]any{"income_multiplier": 2.5})),
+			matching.LoanEstimate{MaxAmount: 125000, TenureMonths: 60, Basis: matching.EstimateIncomeMultiplier}},
+		{"rounded down", user(func(u *models.User) { u.MonthlyIncome = 33333.33 }), product(),
+			matching.LoanEstimate{MaxAmount: 499000, TenureMonths: 60, Basis: matching.EstimateIncomeMultiplier}},
+		{"capped at the maximum amount", user(), product(func(p *models.LoanProduct) { p.MaxLoanAmount = 500000 }),
+			matching.LoanEstimate{MaxAmount: 500000, TenureMonths: 60, Basis: matching.EstimateIncomeMultiplier}},
+		{"below the minimum amount", user(), product(func(p *models.LoanProduct) { p.MinLoanAmount = 1000000 }),
+			matching.LoanEstimate{TenureMonths: 60, Basis: matching.EstimateIncomeMultiplier, BelowMinimum: true}},
+		{"FOIR headroom", user(obligations(10000)), product(withFOIR),
+			matching.LoanEstimate{MaxAmount: 521000, TenureMonths: 60, Basis: matching.EstimateFOIRHeadroom}},
+		{"FOIR headroom over a shorter tenure", user(obligations(10000), requestedTenure(24)), product(withFOIR),
+			matching.LoanEstimate{MaxAmount: 283000, TenureMonths: 24, Basis: matching.EstimateFOIRHeadroom}},
+		{"no FOIR headroom", user(obligations(30000)), product(withFOIR),
+			matching.LoanEstimate{TenureMonths: 60, Basis: matching.EstimateFOIRHeadroom}},
+		{"FOIR limit without a parsed rate", user(obligations(10000)), product(withFOIR, func(p *models.LoanProduct) {
+			p.InterestRate = "contact branch"
+			p.ParseInterestRate()
+		}), matching.LoanEstimate{MaxAmount: 750000, TenureMonths: 60, Basis: matching.EstimateIncomeMultiplier}},
+		{"requested tenure within the product range", user(requestedTenure(24)), product(tenures(12, 48)),
+			matching.LoanEstimate{MaxAmount: 750000, TenureMonths: 24, Basis: matching.EstimateIncomeMultiplier}},
+		{"requested tenure above the product maximum", user(requestedTenure(72)), product(tenures(12, 48)),
+			matching.LoanEstimate{MaxAmount: 750000, TenureMonths: 48, Basis: matching.EstimateIncomeMultiplier}},
+		{"requested tenure below the product minimum", user(requestedTenure(12)), product(tenures(36, 48)),
+			matching.LoanEstimate{MaxAmount: 750000, TenureMonths: 36, Basis: matching.EstimateIncomeMultiplier}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matching.EstimateLoan(tt.user, tt.product); *got != tt.want {
+				t.Errorf("EstimateLoan = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateEstimatesEligibleMatches(t *testing.T) {
+	if res := matching.Evaluate(matchingtest.User(), matchingtest.Product()); res.Estimate == nil {
+		t.Error("eligible result has no estimate")
+	}
+	ineligible := matchingtest.User(func(u *models.User) { u.CreditScore = 600 })
+	if res := matching.Evaluate(ineligible, matchingtest.Product()); res.Estimate != nil {
+		t.Errorf("ineligible result has an estimate: %+v", res.Estimate)
+	}
+}
@@ -0,0 +1,89 @@
+package matching
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/BadadheVed/clickpe/models"
+)
+
+// locationCheck passes when the user's pincode, city or city tier is in any of
+// the product's serviceability lists. Users without a location fail, since
+// the lender cannot confirm it serves them.
+func locationCheck(u *models.User, p *models.LoanProduct) Check {
+	check := Check{Criterion: "location"}
+	where := describeLocation(u)
+	if where == "" {
+		check.Detail = "location unknown; product serves " + describeServiceArea(p)
+		return check
+	}
+
+	check.Passed = servesPincode(p.ServiceablePincodes, u.Pincode) ||
+		servesCity(p.ServiceableCities, u.City) ||
+		(u.CityTier > 0 && slices.Contains(p.CityTiers, u.CityTier))
+	if check.Passed {
+		check.Detail = where + " is serviceable"
+	} else {
+		check.Detail = where + " is outside the service area (" + describeServiceArea(p) + ")"
+	}
+	return check
+}
+
+func servesPincode(pincodes []string, pincode string) bool {
+	pincode = strings.TrimSpace(pincode)
+	if pincode == "" {
+		return false
+	}
+	for _, prefix := range pincodes {
+		if prefix = strings.TrimSpace(prefix); prefix != "" && strings.HasPrefix(pincode, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func servesCity(cities []string, city string) bool {
+	city = strings.TrimSpace(city)
+	if city == "" {
+		return false
+	}
+	for _, c := range cities {
+		if strings.EqualFold(strings.TrimSpace(c), city) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeLocation(u *models.User) string {
+	var parts []string
+	if u.Pincode != "" {
+		parts = append(parts, "pincode "+u.Pincode)
+	}
+	if u.City != "" {
+		parts = append(parts, u.City)
+	}
+	if u.CityTier > 0 {
+		parts = append(parts, fmt.Sprintf("tier %d", u.CityTier))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeServiceArea(p *models.LoanProduct) string {
+	var parts []string
+	if n := len(p.ServiceablePincodes); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d pincode(s)", n))
+	}
+	if len(p.ServiceableCities) > 0 {
+		parts = append(parts, strings.Join(p.ServiceableCities, ", "))
+	}
+	if len(p.CityTiers) > 0 {
+		tiers := make([]string, len(p.CityTiers))
+		for i, t := range p.CityTiers {
+			tiers[i] = fmt.Sprint(t)
+		}
+		parts = append(parts, "tier "+strings.Join(tiers, "/")+" cities")
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,195 @@
+package matching
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/BadadheVed/clickpe/models"
+)
+
+const (
+	MaxCreditScore      = 900
+	ConfidenceThreshold = 0.5
+)
+
+type Check struct {
+	Criterion string `json:"criterion"`
+	Passed    bool   `json:"passed"`
+	Detail    string `json:"detail"`
+
+	// Gap is how far a failed threshold check fell short, in the criterion's
+	// own unit. It is zero for passed and non-numeric checks.
+	Gap float64 `json:"gap,omitempty"`
+}
+
+type Result struct {
+	Eligible      bool           `json:"eligible"`
+	Score         float64        `json:"score"`
+	Checks        []Check        `json:"checks"`
+	Affordability *Affordability `json:"affordability,omitempty"`
+	Estimate      *LoanEstimate  `json:"estimate,omitempty"`
+}
+
+// Reason flattens the checks into the text stored on models.Match.
+func (r Result) Reason() string {
+	parts := make([]string, 0, len(r.Checks))
+	for _, c := range r.Checks {
+		parts = append(parts, c.Detail)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// FailureReason lists only the checks that failed.
+func (r Result) FailureReason() string {
+	var parts []string
+	for _, c := range r.Checks {
+		if !c.Passed {
+			parts = append(parts, c.Detail)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (r Result) Confident() bool {
+	return r.Eligible && r.Score >= ConfidenceThreshold
+}
+
+// Evaluate checks a user against every criterion of a product. All checks are
+// run even after a failure so callers can explain every gap.
+func Evaluate(u *models.User, p *models.LoanProduct) Result {
+	checks := structuredChecks(u, p)
+	if p.RestrictsLocation() {
+		checks = append(checks, locationCheck(u, p))
+	}
+
+	var aff *Affordability
+	if p.MaxFOIR > 0 {
+		var check Check
+		aff, check = AssessAffordability(u, p, p.MaxFOIR)
+		checks = append(checks, check)
+	}
+
+	r := result(u, p, append(checks, residualChecks(u, p)...))
+	r.Affordability = aff
+	if r.Eligible {
+		r.Estimate = EstimateLoan(u, p)
+	}
+	return r
+}
+
+// HasResidual reports whether a product has rules that cannot be pushed into
+// SQL: RawCriteria rules, an affordability limit or a service area.
+func HasResidual(p *models.LoanProduct) bool {
+	if p.MaxFOIR > 0 || p.RestrictsLocation() {
+		return true
+	}
+	c, err := ParseCriteria(p)
+	return err != nil || len(c.RuleRefs()) > 0
+}
+
+func result(u *models.User, p *models.LoanProduct, checks []Check) Result {
+	r := Result{Eligible: true, Checks: checks}
+	for _, check := range checks {
+		if !check.Passed {
+			r.Eligible = false
+			break
+		}
+	}
+	if r.Eligible {
+		r.Score = score(u, p)
+	}
+	return r
+}
+
+func structuredChecks(u *models.User, p *models.LoanProduct) []Check {
+	checks := []Check{
+		{
+			Criterion: "credit_score",
+			Passed:    u.CreditScore >= p.MinCreditScore,
+			Detail:    fmt.Sprintf("credit score %d vs minimum %d", u.CreditScore, p.MinCreditScore),
+			Gap:       shortfall(float64(p.MinCreditScore), float64(u.CreditScore)),
+		},
+		{
+			Criterion: "monthly_income",
+			Passed:    u.MonthlyIncome >= p.MinMonthlyIncome,
+			Detail:    fmt.Sprintf("monthly income %.2f vs minimum %.2f", u.MonthlyIncome, p.MinMonthlyIncome),
+			Gap:       shortfall(p.MinMonthlyIncome, u.MonthlyIncome),
+		},
+	}
+	if p.Age > 0 {
+		checks = append(checks, Check{
+			Criterion: "age",
+			Passed:    u.Age >= p.Age,
+			Detail:    fmt.Sprintf("age %d vs minimum %d", u.Age, p.Age),
+			Gap:       shortfall(float64(p.Age), float64(u.Age)),
+		})
+	}
+	if u.RequestedLoanType != "" {
+		checks = append(checks, loanTypeCheck(u, p))
+	}
+	if u.RequestedAmount > 0 && (p.MinLoanAmount > 0 || p.MaxLoanAmount > 0) {
+		checks = append(checks, loanAmountCheck(u, p))
+	}
+	return checks
+}
+
+// loanTypeCheck passes when the product offers the requested type. Products
+// without a type are not excluded.
+func loanTypeCheck(u *models.User, p *models.LoanProduct) Check {
+	offered := string(p.LoanType)
+	if offered == "" {
+		offered = "unspecified"
+	}
+	return Check{
+		Criterion: "loan_type",
+		Passed:    p.LoanType == "" || p.LoanType == u.RequestedLoanType,
+		Detail:    fmt.Sprintf("requested loan type %s vs product %s", u.RequestedLoanType, offered),
+	}
+}
+
+// loanAmountCheck passes when the requested amount is within the product's
+// range; a zero bound is open.
+func loanAmountCheck(u *models.User, p *models.LoanProduct) Check {
+	check := Check{
+		Criterion: "loan_amount",
+		Passed:    u.RequestedAmount >= p.MinLoanAmount && (p.MaxLoanAmount == 0 || u.RequestedAmount <= p.MaxLoanAmount),
+		Detail:    fmt.Sprintf("requested amount %.2f vs minimum %.2f, maximum %.2f", u.RequestedAmount, p.MinLoanAmount, p.MaxLoanAmount),
+		Gap:       shortfall(p.MinLoanAmount, u.RequestedAmount),
+	}
+	if p.MaxLoanAmount > 0 && u.RequestedAmount > p.MaxLoanAmount {
+		check.Gap = round2(u.RequestedAmount - p.MaxLoanAmount)
+	}
+	return check
+}
+
+func residualChecks(u *models.User, p *models.LoanProduct) []Check {
+	c, err := ParseCriteria(p)
+	if err != nil {
+		return []Check{{Criterion: "raw_criteria", Passed: false, Detail: err.Error()}}
+	}
+
+	refs := c.RuleRefs()
+	checks := make([]Check, 0, len(refs))
+	for _, ref := range refs {
+		checks = append(checks, EvaluateRule(ref, u, p))
+	}
+	return checks
+}
+
+func shortfall(required, actual float64) float64 {
+	return round2(math.Max(0, required-actual))
+}
+
+// score is the average headroom above the credit and income minimums, in [0, 1].
+func score(u *models.User, p *models.LoanProduct) float64 {
+	credit := 1.0
+	if span := MaxCreditScore - p.MinCreditScore; span > 0 {
+		credit = float64(u.CreditScore-p.MinCreditScore) / float64(span)
+	}
+	income := 1.0
+	if p.MinMonthlyIncome > 0 {
+		income = (u.MonthlyIncome - p.MinMonthlyIncome) / p.MinMonthlyIncome
+	}
+	return round2((math.Min(credit, 1) + math.Min(income, 1)) / 2)
+}
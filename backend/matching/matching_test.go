@@ -0,0 +1,73 @@
+package matching_test
+
+import (
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+)
+
+func findCheck(res matching.Result, criterion string) (matching.Check, bool) {
+	for _, c := range res.Checks {
+		if c.Criterion == criterion {
+			return c, true
+		}
+	}
+	return matching.Check{}, false
+}
+
+func TestLoanTypeAndAmountChecks(t *testing.T) {
+	request := func(loanType models.LoanType, amount float64) *models.User {
+		return matchingtest.User(func(u *models.User) { u.RequestedLoanType, u.RequestedAmount = loanType, amount })
+	}
+	offers := func(loanType models.LoanType, min, max float64) *models.LoanProduct {
+		return matchingtest.Product(func(p *models.LoanProduct) { p.LoanType, p.MinLoanAmount, p.MaxLoanAmount = loanType, min, max })
+	}
+
+	tests := []struct {
+		name      string
+		user      *models.User
+		product   *models.LoanProduct
+		criterion string
+		checked   bool
+		pass      bool
+		gap       float64
+		detail    string
+	}{
+		{name: "no type requested", user: request("", 0), product: offers(models.LoanTypeHome, 0, 0), criterion: "loan_type"},
+		{name: "type offered", user: request(models.LoanTypeHome, 0), product: offers(models.LoanTypeHome, 0, 0),
+			criterion: "loan_type", checked: true, pass: true, detail: "requested loan type home vs product home"},
+		{name: "product without a type", user: request(models.LoanTypeHome, 0), product: offers("", 0, 0),
+			criterion: "loan_type", checked: true, pass: true, detail: "requested loan type home vs product unspecified"},
+		{name: "other type", user: request(models.LoanTypeHome, 0), product: offers(models.LoanTypePersonal, 0, 0),
+			criterion: "loan_type", checked: true, detail: "requested loan type home vs product personal"},
+
+		{name: "no amount requested", user: request("", 0), product: offers("", 100000, 500000), criterion: "loan_amount"},
+		{name: "product without a range", user: request("", 300000), product: offers("", 0, 0), criterion: "loan_amount"},
+		{name: "within range", user: request("", 300000), product: offers("", 100000, 500000),
+			criterion: "loan_amount", checked: true, pass: true, detail: "requested amount 300000.00 vs minimum 100000.00, maximum 500000.00"},
+		{name: "at both bounds", user: request("", 500000), product: offers("", 500000, 500000), criterion: "loan_amount", checked: true, pass: true},
+		{name: "open maximum", user: request("", 5000000), product: offers("", 100000, 0), criterion: "loan_amount", checked: true, pass: true},
+		{name: "below minimum", user: request("", 75000), product: offers("", 100000, 500000), criterion: "loan_amount", checked: true, gap: 25000},
+		{name: "above maximum", user: request("", 650000.5), product: offers("", 100000, 500000), criterion: "loan_amount", checked: true, gap: 150000.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := matching.Evaluate(tt.user, tt.product)
+			check, checked := findCheck(res, tt.criterion)
+			if checked != tt.checked {
+				t.Fatalf("%s checked = %t, want %t", tt.criterion, checked, tt.checked)
+			}
+			if !checked {
+				return
+			}
+			if check.Passed != tt.pass || check.Gap != tt.gap || res.Eligible != tt.pass {
+				t.Errorf("check = %+v (eligible %t), want passed %t with gap %v", check, res.Eligible, tt.pass, tt.gap)
+			}
+			if tt.detail != "" && check.Detail != tt.detail {
+				t.Errorf("detail = %q, want %q", check.Detail, tt.detail)
+			}
+		})
+	}
+}
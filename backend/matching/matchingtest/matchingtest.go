@@ -0,0 +1,112 @@
+// Package matchingtest provides helpers for authors of matching rules: fixture
+// builders for users and products, and assertions that run a rule the same
+// way the engine does.
+package matchingtest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+// User returns an eligible-looking salaried borrower, adjusted by opts.
+func User(opts ...func(*models.User)) *models.User {
+	u := &models.User{
+		ID:               uuid.New(),
+		Name:             "Test User",
+		Email:            "test@example.com",
+		Age:              30,
+		MonthlyIncome:    50000,
+		CreditScore:      750,
+		EmploymentStatus: "salaried",
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// Product returns a product with permissive criteria, adjusted by opts.
+func Product(opts ...func(*models.LoanProduct)) *models.LoanProduct {
+	p := &models.LoanProduct{
+		ID:               uuid.New(),
+		BankName:         "Test Bank",
+		ProductName:      "Test Personal Loan",
+		InterestRate:     "10.5% - 24% p.a.",
+		MinCreditScore:   650,
+		MinMonthlyIncome: 15000,
+		Age:              21,
+		ProductURL:       "https://example.com/loan",
+	}
+	p.ParseInterestRate()
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// WithRawCriteria sets RawCriteria from any JSON-serialisable value.
+func WithRawCriteria(v any) func(*models.LoanProduct) {
+	return func(p *models.LoanProduct) {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		p.RawCriteria = raw
+	}
+}
+
+// WithRules references registered rules from RawCriteria.
+func WithRules(refs ...matching.RuleRef) func(*models.LoanProduct) {
+	return WithRawCriteria(map[string]any{"rules": refs})
+}
+
+// Ref builds a rule reference with params marshalled to JSON.
+func Ref(name string, params any) matching.RuleRef {
+	ref := matching.RuleRef{Name: name}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			panic(err)
+		}
+		ref.Params = raw
+	}
+	return ref
+}
+
+// Eval runs a rule directly with the given params.
+func Eval(rule matching.Rule, u *models.User, p *models.LoanProduct, params any) matching.Check {
+	ref := Ref(rule.Name(), params)
+	check := rule.Evaluate(&matching.Context{User: u, Product: p, Params: ref.Params})
+	if check.Criterion == "" {
+		check.Criterion = rule.Name()
+	}
+	return check
+}
+
+func AssertPasses(t testing.TB, rule matching.Rule, u *models.User, p *models.LoanProduct, params any) {
+	t.Helper()
+	if check := Eval(rule, u, p, params); !check.Passed {
+		t.Errorf("rule %s: expected pass, got fail: %s", rule.Name(), check.Detail)
+	}
+}
+
+func AssertFails(t testing.TB, rule matching.Rule, u *models.User, p *models.LoanProduct, params any) {
+	t.Helper()
+	if check := Eval(rule, u, p, params); check.Passed {
+		t.Errorf("rule %s: expected fail, got pass: %s", rule.Name(), check.Detail)
+	}
+}
+
+// AssertEligible evaluates the full product, including registered rules
+// referenced from RawCriteria, exactly as persisted matching does.
+func AssertEligible(t testing.TB, u *models.User, p *models.LoanProduct, want bool) {
+	t.Helper()
+	res := matching.Evaluate(u, p)
+	if res.Eligible != want {
+		t.Errorf("expected eligible=%t, got %t: %s", want, res.Eligible, res.Reason())
+	}
+}
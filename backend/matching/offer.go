@@ -0,0 +1,25 @@
+package matching
+
+import "github.com/BadadheVed/clickpe/models"
+
+type Offer struct {
+	Rank    int                `json:"rank"`
+	Product models.LoanProduct `json:"product"`
+	Result
+}
+
+// EligibleOffers evaluates a user against every product and returns the
+// eligible ones ordered by the ranker. The user does not need to be persisted.
+func EligibleOffers(u *models.User, products []models.LoanProduct, r Ranker) []Offer {
+	offers := make([]Offer, 0, len(products))
+	for i := range products {
+		res := Evaluate(u, &products[i])
+		if !res.Eligible {
+			continue
+		}
+		offers = append(offers, Offer{Product: products[i], Result: res})
+	}
+
+	Rank(offers, r)
+	return offers
+}
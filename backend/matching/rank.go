@@ -0,0 +1,108 @@
+package matching
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Ranker orders eligible offers. Less reports whether a should be shown before b.
+type Ranker interface {
+	Less(a, b *Offer) bool
+}
+
+type RankFunc func(a, b *Offer) bool
+
+func (f RankFunc) Less(a, b *Offer) bool { return f(a, b) }
+
+const DefaultRanker = "rate"
+
+var (
+	rankersMu sync.RWMutex
+	rankers   = map[string]Ranker{
+		"rate":       RankFunc(byEffectiveRate),
+		"max_rate":   RankFunc(byMaxRate),
+		"score":      RankFunc(byScore),
+		"min_income": RankFunc(byMinIncome),
+	}
+)
+
+// RegisterRanker adds or replaces a ranking strategy.
+func RegisterRanker(name string, r Ranker) {
+	rankersMu.Lock()
+	defer rankersMu.Unlock()
+	rankers[name] = r
+}
+
+// LookupRanker returns the named strategy, or DefaultRanker for an empty name.
+func LookupRanker(name string) (Ranker, error) {
+	if name == "" {
+		name = DefaultRanker
+	}
+	rankersMu.RLock()
+	defer rankersMu.RUnlock()
+	r, ok := rankers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ranking strategy %q", name)
+	}
+	return r, nil
+}
+
+func RankerNames() []string {
+	rankersMu.RLock()
+	defer rankersMu.RUnlock()
+	names := make([]string, 0, len(rankers))
+	for name := range rankers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rank sorts offers in place and assigns 1-based ranks.
+func Rank(offers []Offer, r Ranker) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		return r.Less(&offers[i], &offers[j])
+	})
+	for i := range offers {
+		offers[i].Rank = i + 1
+	}
+}
+
+// byEffectiveRate puts the cheapest advertised APR first. Offers without a
+// parsed rate go last; ties fall back to score.
+func byEffectiveRate(a, b *Offer) bool {
+	ra, okA := a.Product.RateRange().Effective()
+	rb, okB := b.Product.RateRange().Effective()
+	if okA != okB {
+		return okA
+	}
+	if ra != rb {
+		return ra < rb
+	}
+	return byScore(a, b)
+}
+
+// byMaxRate ranks on the worst-case APR, for borrowers who want a ceiling.
+func byMaxRate(a, b *Offer) bool {
+	ma, mb := a.Product.MaxAPR, b.Product.MaxAPR
+	if (ma != nil) != (mb != nil) {
+		return ma != nil
+	}
+	if ma != nil && *ma != *mb {
+		return *ma < *mb
+	}
+	return byEffectiveRate(a, b)
+}
+
+func byScore(a, b *Offer) bool {
+	return a.Score > b.Score
+}
+
+// byMinIncome puts the easiest products to qualify for first.
+func byMinIncome(a, b *Offer) bool {
+	if a.Product.MinMonthlyIncome != b.Product.MinMonthlyIncome {
+		return a.Product.MinMonthlyIncome < b.Product.MinMonthlyIncome
+	}
+	return byScore(a, b)
+}
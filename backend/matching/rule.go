@@ -0,0 +1,125 @@
+package matching
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/BadadheVed/clickpe/models"
+)
+
+// Rule is an eligibility check that cannot be expressed with the structured
+// product columns. Rules are compiled in, registered by name from an init
+// function, and referenced from a product's RawCriteria:
+//
+//	{"rules": ["employment_status_salaried", {"name": "foir", "params": {"max": 0.5}}]}
+type Rule interface {
+	Name() string
+	Evaluate(ctx *Context) Check
+}
+
+// Context gives a rule access to the borrower, the product and the
+// parameters the product supplied for this rule.
+type Context struct {
+	User    *models.User
+	Product *models.LoanProduct
+	Params  json.RawMessage
+}
+
+// DecodeParams unmarshals the rule parameters into v. Missing parameters
+// leave v untouched.
+func (c *Context) DecodeParams(v any) error {
+	if len(c.Params) == 0 || string(c.Params) == "null" {
+		return nil
+	}
+	return json.Unmarshal(c.Params, v)
+}
+
+type ruleFunc struct {
+	name string
+	fn   func(ctx *Context) Check
+}
+
+func (r ruleFunc) Name() string { return r.name }
+
+func (r ruleFunc) Evaluate(ctx *Context) Check { return r.fn(ctx) }
+
+// NewRule adapts a function to the Rule interface.
+func NewRule(name string, fn func(ctx *Context) Check) Rule {
+	return ruleFunc{name: name, fn: fn}
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]Rule{}
+)
+
+// Register makes a rule available by name. It panics if the name is empty or
+// already taken, since both are programming errors.
+func Register(r Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	name := r.Name()
+	if name == "" {
+		panic("matching: Register rule with empty name")
+	}
+	if _, dup := rules[name]; dup {
+		panic("matching: Register called twice for rule " + name)
+	}
+	rules[name] = r
+}
+
+func LookupRule(name string) (Rule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	r, ok := rules[name]
+	return r, ok
+}
+
+func RuleNames() []string {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RuleRef is a reference to a registered rule in RawCriteria, either a bare
+// name or an object with name and params.
+type RuleRef struct {
+	Name   string          `json:"name"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+func (r *RuleRef) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		r.Name = name
+		return nil
+	}
+	type plain RuleRef
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("rule must be a name or {\"name\", \"params\"}: %w", err)
+	}
+	*r = RuleRef(p)
+	return nil
+}
+
+// EvaluateRule runs one rule reference. Unknown rules fail closed so a typo in
+// the criteria never widens eligibility.
+func EvaluateRule(ref RuleRef, u *models.User, p *models.LoanProduct) Check {
+	rule, ok := LookupRule(ref.Name)
+	if !ok {
+		return Check{Criterion: ref.Name, Passed: false, Detail: fmt.Sprintf("unknown rule %q", ref.Name)}
+	}
+	check := rule.Evaluate(&Context{User: u, Product: p, Params: ref.Params})
+	if check.Criterion == "" {
+		check.Criterion = ref.Name
+	}
+	return check
+}
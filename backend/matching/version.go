@@ -0,0 +1,35 @@
+package matching
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// EngineVersion must be bumped whenever evaluation, scoring or estimation
+// logic in this package changes.
+const EngineVersion = "1.0.0"
+
+// VersionedRule lets a rule declare a version. Bump it when the rule's logic
+// changes so RulesetVersion changes with it. Rules without one count as "1".
+type VersionedRule interface {
+	Rule
+	Version() string
+}
+
+// RulesetVersion identifies the set of registered rules and their versions.
+func RulesetVersion() string {
+	names := RuleNames()
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		rule, _ := LookupRule(name)
+		version := "1"
+		if v, ok := rule.(VersionedRule); ok {
+			version = v.Version()
+		}
+		parts = append(parts, fmt.Sprintf("%s@%s", name, version))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, ",")))
+	return hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,101 @@
+package matching_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+)
+
+func TestCriteriaHash(t *testing.T) {
+	base := matchingtest.Product()
+	tests := []struct {
+		name    string
+		change  func(*models.LoanProduct)
+		changes bool
+	}{
+		{"product name", func(p *models.LoanProduct) { p.ProductName = "Renamed Loan" }, false},
+		{"bank name", func(p *models.LoanProduct) { p.BankName = "Renamed Bank" }, false},
+		{"product URL", func(p *models.LoanProduct) { p.ProductURL = "https://example.com/moved" }, false},
+		{"minimum credit score", func(p *models.LoanProduct) { p.MinCreditScore++ }, true},
+		{"minimum income", func(p *models.LoanProduct) { p.MinMonthlyIncome++ }, true},
+		{"age", func(p *models.LoanProduct) { p.Age++ }, true},
+		{"FOIR limit", func(p *models.LoanProduct) { p.MaxFOIR = 0.5 }, true},
+		{"interest rate", func(p *models.LoanProduct) {
+			p.InterestRate = "12% p.a."
+			p.ParseInterestRate()
+		}, true},
+		{"loan amount range", func(p *models.LoanProduct) { p.MaxLoanAmount = 500000 }, true},
+		{"tenure range", func(p *models.LoanProduct) { p.MinTenureMonths = 12 }, true},
+		{"loan type", func(p *models.LoanProduct) { p.LoanType = models.LoanTypeHome }, true},
+		{"service area", func(p *models.LoanProduct) { p.ServiceableCities = []string{"Pune"} }, true},
+		{"rules", matchingtest.WithRules(matchingtest.Ref("employment_status_salaried", nil)), true},
+	}
+	want := matching.CriteriaHash(base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := *base
+			tt.change(&p)
+			if got := matching.CriteriaHash(&p); (got != want) != tt.changes {
+				t.Errorf("hash changed = %t, want %t", got != want, tt.changes)
+			}
+		})
+	}
+
+	ordered := func(raw string) *models.LoanProduct {
+		return matchingtest.Product(func(p *models.LoanProduct) { p.RawCriteria = []byte(raw) })
+	}
+	if matching.CriteriaHash(ordered(`{"income_multiplier": 2, "max_age": 60}`)) !=
+		matching.CriteriaHash(ordered(`{"max_age": 60, "income_multiplier": 2}`)) {
+		t.Error("hash depends on raw criteria key order")
+	}
+}
+
+func TestSnapshotRoundTrips(t *testing.T) {
+	p := matchingtest.Product(
+		matchingtest.WithRules(matchingtest.Ref("max_age", map[string]any{"max": 60})),
+		func(p *models.LoanProduct) {
+			p.MaxFOIR, p.LoanType, p.MinTenureMonths, p.MaxTenureMonths = 0.5, models.LoanTypePersonal, 12, 60
+			p.ServiceablePincodes = []string{"411001"}
+		},
+	)
+	want := matching.Snapshot(p)
+	raw, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("marshal snapshot: %v", err)
+	}
+	var got matching.CriteriaSnapshot
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("unmarshal snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot after a round trip = %+v, want %+v", got, want)
+	}
+	if *got.MaxAPR != 24 || len(got.Criteria.Rules) != 1 {
+		t.Errorf("snapshot = %+v, want the parsed APR and the rule", got)
+	}
+}
+
+type versionedRule struct {
+	matching.Rule
+	version string
+}
+
+func (r versionedRule) Version() string { return r.version }
+
+func TestRulesetVersion(t *testing.T) {
+	before := matching.RulesetVersion()
+	if len(before) != 16 || matching.RulesetVersion() != before {
+		t.Fatalf("RulesetVersion = %q, want a stable 16 character hash", before)
+	}
+	matching.Register(versionedRule{
+		Rule:    matching.NewRule("ruleset_version_test", func(*matching.Context) matching.Check { return matching.Check{Passed: true} }),
+		version: "2",
+	})
+	if after := matching.RulesetVersion(); after == before {
+		t.Error("RulesetVersion did not change when a rule was registered")
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Bank is the canonical lender behind one or more products. Aliases hold the
+// other spellings of the name that the crawler has produced.
+type Bank struct {
+	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"bank_id"`
+
+	Name    string                      `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	Aliases datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"aliases"`
+	LogoURL string                      `gorm:"type:text" json:"logo_url"`
+
+	ContactEmail  string `gorm:"type:varchar(255)" json:"contact_email"`
+	ContactPhone  string `gorm:"type:varchar(30)" json:"contact_phone"`
+	WebhookURL    string `gorm:"type:text" json:"webhook_url"`
+	WebhookSecret string `gorm:"type:varchar(255)" json:"-"`
+
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type BankMergeStatus string
+
+const (
+	BankMergePending   BankMergeStatus = "pending"
+	BankMergeConfirmed BankMergeStatus = "confirmed"
+	BankMergeRejected  BankMergeStatus = "rejected"
+)
+
+// BankMerge proposes that several free-text bank names on products are the
+// same lender. Nothing is linked until an admin confirms it.
+type BankMerge struct {
+	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"merge_id"`
+
+	NormalizedName string                      `gorm:"type:varchar(100);not null;uniqueIndex" json:"normalized_name"`
+	CanonicalName  string                      `gorm:"type:varchar(100);not null" json:"canonical_name"`
+	Names          datatypes.JSONSlice[string] `gorm:"type:jsonb;not null" json:"names"`
+	Products       int                         `json:"products"`
+	Similarity     float64                     `gorm:"type:numeric(4,2)" json:"similarity"`
+
+	// BankID is the existing bank the names resolve to, if any, and the bank
+	// they were linked to once confirmed.
+	BankID *uuid.UUID `gorm:"type:uuid;index" json:"bank_id,omitempty"`
+	Bank   *Bank      `gorm:"foreignKey:BankID;constraint:OnDelete:SET NULL" json:"-"`
+
+	Status     BankMergeStatus `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	CreatedAt  time.Time       `gorm:"autoCreateTime" json:"created_at"`
+	ResolvedAt *time.Time      `json:"resolved_at,omitempty"`
+}
@@ -3,20 +3,151 @@ package models
 import (
 	"time"
 
+	"github.com/BadadheVed/clickpe/rates"
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
+// ProductStatus controls whether a product takes part in matching.
+type ProductStatus string
+
+const (
+	ProductStatusActive        ProductStatus = "active"
+	ProductStatusInactive      ProductStatus = "inactive"
+	ProductStatusPendingReview ProductStatus = "pending_review"
+	ProductStatusRejected      ProductStatus = "rejected"
+	ProductStatusMerged        ProductStatus = "merged"
+)
+
+// LoanType is the kind of loan a product offers or a borrower asks for.
+type LoanType string
+
+const (
+	LoanTypePersonal  LoanType = "personal"
+	LoanTypeHome      LoanType = "home"
+	LoanTypeGold      LoanType = "gold"
+	LoanTypeBusiness  LoanType = "business"
+	LoanTypeEducation LoanType = "education"
+)
+
+var LoanTypes = []LoanType{LoanTypePersonal, LoanTypeHome, LoanTypeGold, LoanTypeBusiness, LoanTypeEducation}
+
+// ValidLoanType reports whether t is a known loan type. The empty type means
+// unspecified and is also valid.
+func ValidLoanType(t LoanType) bool {
+	if t == "" {
+		return true
+	}
+	for _, known := range LoanTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
 type LoanProduct struct {
-	ID               uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"product_id"`
-	BankName         string         `gorm:"type:varchar(100);not null" json:"bank_name"`
-	ProductName      string         `gorm:"type:varchar(255);not null" json:"product_name"`
-	InterestRate     string         `gorm:"type:varchar(50)" json:"interest_rate"`
-	MinCreditScore   int            `gorm:"default:0" json:"min_credit_score"`
-	MinMonthlyIncome float64        `gorm:"type:numeric(12,2);default:0" json:"min_monthly_income"`
-	RawCriteria      datatypes.JSON `gorm:"type:jsonb" json:"raw_criteria"`
-	ProductURL       string         `gorm:"type:text;uniqueIndex" json:"product_url"`
-	Age              int            `gorm:"constraint:check=age>=18;column:age;" json:"age"`
-	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt        *time.Time     `json:"updated_at"`
+	ID               uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"product_id"`
+	BankName         string     `gorm:"type:varchar(100);not null" json:"bank_name"`
+	BankID           *uuid.UUID `gorm:"type:uuid;index" json:"bank_id"`
+	Bank             *Bank      `gorm:"foreignKey:BankID;constraint:OnDelete:SET NULL" json:"-"`
+	ProductName      string     `gorm:"type:varchar(255);not null" json:"product_name"`
+	LoanType         LoanType   `gorm:"type:varchar(20);index" json:"loan_type"`
+	InterestRate     string     `gorm:"type:varchar(50)" json:"interest_rate"`
+	MinAPR           *float64   `gorm:"type:numeric(6,2);column:min_apr" json:"min_apr"`
+	MaxAPR           *float64   `gorm:"type:numeric(6,2);column:max_apr" json:"max_apr"`
+	RateBasis        string     `gorm:"type:varchar(20)" json:"rate_basis"`
+	MinCreditScore   int        `gorm:"default:0" json:"min_credit_score"`
+	MinMonthlyIncome float64    `gorm:"type:numeric(12,2);default:0" json:"min_monthly_income"`
+	MaxFOIR          float64    `gorm:"type:numeric(4,2);default:0;column:max_foir" json:"max_foir"`
+	MinLoanAmount    float64    `gorm:"type:numeric(14,2);default:0" json:"min_loan_amount"`
+	MaxLoanAmount    float64    `gorm:"type:numeric(14,2);default:0" json:"max_loan_amount"`
+	MinTenureMonths  int        `gorm:"default:0" json:"min_tenure_months"`
+	MaxTenureMonths  int        `gorm:"default:0" json:"max_tenure_months"`
+
+	// The processing fee is ProcessingFeeFlat plus ProcessingFeePercent of
+	// the loan amount.
+	ProcessingFeePercent float64        `gorm:"type:numeric(5,2);default:0" json:"processing_fee_percent"`
+	ProcessingFeeFlat    float64        `gorm:"type:numeric(12,2);default:0" json:"processing_fee_flat"`
+	RawCriteria          datatypes.JSON `gorm:"type:jsonb" json:"raw_criteria"`
+
+	// Serviceability. Pincode entries shorter than six digits match as
+	// prefixes. Empty lists mean the product is available everywhere.
+	ServiceablePincodes datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"serviceable_pincodes"`
+	ServiceableCities   datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"serviceable_cities"`
+	CityTiers           datatypes.JSONSlice[int]    `gorm:"type:jsonb" json:"city_tiers"`
+
+	ProductURL    string     `gorm:"type:text;uniqueIndex" json:"product_url"`
+	Age           int        `gorm:"constraint:check=age>=18;column:age;" json:"age"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+	CriteriaHash  string     `gorm:"type:varchar(64)" json:"-"`
+	LastMatchedAt *time.Time `json:"last_matched_at"`
+
+	// Only active products are matched. Status is set to inactive when the
+	// crawler stops seeing the product, and to pending_review when crawled
+	// criteria need an admin's approval. Duplicates are set to merged, for
+	// good, with MergedIntoID naming the surviving product. LastSeenAt is
+	// refreshed by crawler ingestion and stays nil for products the crawler
+	// has never listed, which are never deactivated for going unseen.
+	Status          ProductStatus `gorm:"type:varchar(20);not null;default:active;index" json:"status"`
+	StatusReason    string        `gorm:"type:text" json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time    `json:"status_changed_at,omitempty"`
+	LastSeenAt      *time.Time    `gorm:"index" json:"last_seen_at"`
+	MergedIntoID    *uuid.UUID    `gorm:"type:uuid;index" json:"merged_into_id,omitempty"`
+
+	// ApprovedVersion is the ProductHistory version last approved for
+	// matching; 0 until the product is first approved.
+	ApprovedVersion int `gorm:"default:0" json:"approved_version"`
+}
+
+// ParseInterestRate refreshes the numeric APR fields from InterestRate and
+// reports whether they changed. The raw string is left untouched.
+func (p *LoanProduct) ParseInterestRate() bool {
+	r, _ := rates.Parse(p.InterestRate)
+	changed := !sameRate(p.MinAPR, r.MinAPR) || !sameRate(p.MaxAPR, r.MaxAPR) || p.RateBasis != string(r.Basis)
+	p.MinAPR, p.MaxAPR, p.RateBasis = r.MinAPR, r.MaxAPR, string(r.Basis)
+	return changed
+}
+
+func (p *LoanProduct) RateRange() rates.Range {
+	return rates.Range{MinAPR: p.MinAPR, MaxAPR: p.MaxAPR, Basis: rates.Basis(p.RateBasis)}
+}
+
+// Active reports whether the product takes part in matching. Products built
+// in memory without a status count as active.
+func (p *LoanProduct) Active() bool {
+	return p.Status == "" || p.Status == ProductStatusActive
+}
+
+// ProcessingFee is the fee charged on a loan of the given amount.
+func (p *LoanProduct) ProcessingFee(amount float64) float64 {
+	return p.ProcessingFeeFlat + amount*p.ProcessingFeePercent/100
+}
+
+// DisplayBankName is the canonical bank name when the bank is loaded, else
+// the name as crawled.
+func (p *LoanProduct) DisplayBankName() string {
+	if p.Bank != nil && p.Bank.Name != "" {
+		return p.Bank.Name
+	}
+	return p.BankName
+}
+
+// RestrictsLocation reports whether the product is only offered in some areas.
+func (p *LoanProduct) RestrictsLocation() bool {
+	return len(p.ServiceablePincodes) > 0 || len(p.ServiceableCities) > 0 || len(p.CityTiers) > 0
+}
+
+func (p *LoanProduct) BeforeSave(tx *gorm.DB) error {
+	p.ParseInterestRate()
+	return nil
+}
+
+func sameRate(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
@@ -4,19 +4,49 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+type MatchStatus string
+
+const (
+	MatchStatusActive     MatchStatus = "active"
+	MatchStatusSuperseded MatchStatus = "superseded"
+	MatchStatusExpired    MatchStatus = "expired"
+	MatchStatusWithdrawn  MatchStatus = "withdrawn"
 )
 
 type Match struct {
 	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"match_id"`
 
-	UserID uuid.UUID `gorm:"type:uuid;not null;index;column:user_id" json:"user_id"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_matches_user_product,priority:1;column:user_id" json:"user_id"`
 	User   User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE;" json:"-"`
 
-	ProductID   uuid.UUID   `gorm:"type:uuid;not null;index;column:product_id" json:"product_id"`
+	ProductID   uuid.UUID   `gorm:"type:uuid;not null;index;uniqueIndex:idx_matches_user_product,priority:2;column:product_id" json:"product_id"`
 	LoanProduct LoanProduct `gorm:"constraint:OnDelete:CASCADE;foreignKey:ProductID" json:"-"`
 
 	MatchConfidence bool      `gorm:"default:false" json:"match_confidence"`
+	Score           float64   `gorm:"type:numeric(4,2);default:0" json:"score"`
 	IsNotified      bool      `gorm:"default:false" json:"is_notified"`
 	MatchedAt       time.Time `gorm:"autoCreateTime" json:"matched_at"`
 	Reason          string    `gorm:"type:text" json:"reason"`
+
+	EstimatedMaxAmount    float64 `gorm:"type:numeric(14,2);default:0" json:"estimated_max_amount"`
+	EstimatedTenureMonths int     `gorm:"default:0" json:"estimated_tenure_months"`
+
+	// Explanation is the full matching.Result, including EMI and FOIR.
+	Explanation datatypes.JSON `gorm:"type:jsonb" json:"explanation,omitempty"`
+
+	// RunID, the versions and CriteriaSnapshot describe the evaluation that
+	// last changed this match, so the decision can be reproduced later.
+	RunID            *uuid.UUID     `gorm:"type:uuid;index" json:"run_id,omitempty"`
+	EngineVersion    string         `gorm:"type:varchar(20)" json:"engine_version,omitempty"`
+	RulesetVersion   string         `gorm:"type:varchar(64)" json:"ruleset_version,omitempty"`
+	CriteriaSnapshot datatypes.JSON `gorm:"type:jsonb" json:"criteria_snapshot,omitempty"`
+
+	Status          MatchStatus `gorm:"type:varchar(20);not null;default:active;index" json:"status"`
+	StatusReason    string      `gorm:"type:text" json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time  `json:"status_changed_at,omitempty"`
+	ValidFrom       time.Time   `gorm:"not null;default:now()" json:"valid_from"`
+	ValidUntil      *time.Time  `gorm:"index" json:"valid_until,omitempty"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MatchRunStatus string
+
+const (
+	MatchRunRunning   MatchRunStatus = "running"
+	MatchRunCompleted MatchRunStatus = "completed"
+	MatchRunFailed    MatchRunStatus = "failed"
+	MatchRunCancelled MatchRunStatus = "cancelled"
+)
+
+// MatchRun records one execution of the matching engine, so every match can
+// be traced to the engine and ruleset that produced it.
+type MatchRun struct {
+	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"run_id"`
+
+	Mode           string     `gorm:"type:varchar(20);not null" json:"mode"`
+	Trigger        string     `gorm:"type:varchar(30);not null" json:"trigger"`
+	ProductID      *uuid.UUID `gorm:"type:uuid;index" json:"product_id,omitempty"`
+	EngineVersion  string     `gorm:"type:varchar(20);not null" json:"engine_version"`
+	RulesetVersion string     `gorm:"type:varchar(64);not null" json:"ruleset_version"`
+
+	Status      MatchRunStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Error       string         `gorm:"type:text" json:"error,omitempty"`
+	Products    int            `json:"products"`
+	Upserted    int            `json:"upserted"`
+	Invalidated int            `json:"invalidated"`
+	StartedAt   time.Time      `gorm:"autoCreateTime;index" json:"started_at"`
+	FinishedAt  *time.Time     `json:"finished_at,omitempty"`
+}
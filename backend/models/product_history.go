@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// ProductChangeSource says who changed a product.
+type ProductChangeSource string
+
+const (
+	ProductSourceCrawler ProductChangeSource = "crawler"
+	ProductSourceAPI     ProductChangeSource = "api"
+	ProductSourceAdmin   ProductChangeSource = "admin"
+)
+
+// ProductHistory is one version of a product's eligibility criteria. Version
+// 1 is the first criteria seen for the product.
+type ProductHistory struct {
+	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+
+	ProductID   uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex:idx_product_history_version,priority:1" json:"product_id"`
+	LoanProduct LoanProduct `gorm:"constraint:OnDelete:CASCADE;foreignKey:ProductID" json:"-"`
+	Version     int         `gorm:"not null;uniqueIndex:idx_product_history_version,priority:2" json:"version"`
+
+	Source ProductChangeSource `gorm:"type:varchar(20);not null" json:"source"`
+
+	// Diff lists the changed fields with their old and new values; Criteria is
+	// the full set of criteria after the change.
+	Diff     datatypes.JSON `gorm:"type:jsonb;not null" json:"diff"`
+	Criteria datatypes.JSON `gorm:"type:jsonb;not null" json:"criteria"`
+
+	ChangedAt time.Time `gorm:"not null;index" json:"changed_at"`
+}
@@ -16,5 +16,14 @@ type User struct {
 	CreditScore   int     `gorm:"not null;index:idx_users_credit_score" json:"credit_score"`
 
 	EmploymentStatus string `gorm:"type:varchar(50)" json:"employment_status"`
-	CreatedAt        time.Time
+
+	MonthlyObligations    float64  `gorm:"type:numeric(12,2);default:0" json:"monthly_obligations"`
+	RequestedAmount       float64  `gorm:"type:numeric(14,2);default:0" json:"requested_amount"`
+	RequestedTenureMonths int      `gorm:"default:0" json:"requested_tenure_months"`
+	RequestedLoanType     LoanType `gorm:"type:varchar(20)" json:"requested_loan_type"`
+
+	Pincode   string `gorm:"type:varchar(10);index:idx_users_pincode" json:"pincode"`
+	City      string `gorm:"type:varchar(100)" json:"city"`
+	CityTier  int    `gorm:"default:0" json:"city_tier"`
+	CreatedAt time.Time
 }
@@ -0,0 +1,122 @@
+// Package rates parses the free-text interest rates scraped from bank sites
+// into a numeric annual percentage range.
+package rates
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Basis string
+
+const (
+	BasisAnnual  Basis = "annual"
+	BasisMonthly Basis = "monthly"
+	BasisFlat    Basis = "flat"
+	BasisUnknown Basis = "unknown"
+)
+
+// FlatTenureMonths is the tenure assumed when converting a flat rate to an
+// effective annual rate.
+const FlatTenureMonths = 36
+
+var ErrNoRate = errors.New("no interest rate found")
+
+type Range struct {
+	MinAPR *float64 `json:"min_apr"`
+	MaxAPR *float64 `json:"max_apr"`
+	Basis  Basis    `json:"basis"`
+}
+
+// Effective is the rate used to compare offers: the lowest advertised APR,
+// or the ceiling when only an "up to" rate is published.
+func (r Range) Effective() (float64, bool) {
+	if r.MinAPR != nil {
+		return *r.MinAPR, true
+	}
+	if r.MaxAPR != nil {
+		return *r.MaxAPR, true
+	}
+	return 0, false
+}
+
+var (
+	// percentPattern prefers numbers tagged with %, so tenures such as
+	// "for 24 months" are not mistaken for rates. A range may omit the % on
+	// its lower bound ("10.5 - 24%").
+	percentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%?\s*(?:-|–|to)\s*(\d+(?:\.\d+)?)\s*%|(\d+(?:\.\d+)?)\s*%`)
+	numberPattern  = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	monthlyPattern = regexp.MustCompile(`per\s*month|\bp\.?\s*m\.?\b|/\s*month|monthly`)
+	lowerOnly      = regexp.MustCompile(`\b(starting|starts|from|min(imum)?|as low as)\b`)
+	upperOnly      = regexp.MustCompile(`\b(up\s*to|upto|max(imum)?)\b`)
+)
+
+// Parse handles strings such as "10.5% - 24% p.a.", "starting 1.2% per month",
+// "up to 18%" and "9.99% flat". Monthly rates are multiplied by 12 and flat
+// rates are converted to an approximate effective APR.
+func Parse(raw string) (Range, error) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	nums := rateNumbers(s)
+	if len(nums) == 0 {
+		return Range{Basis: BasisUnknown}, ErrNoRate
+	}
+
+	values := make([]float64, 0, len(nums))
+	for _, n := range nums {
+		v, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return Range{Basis: BasisUnknown}, err
+		}
+		values = append(values, v)
+	}
+
+	r := Range{Basis: BasisAnnual}
+	factor := 1.0
+	switch {
+	case strings.Contains(s, "flat"):
+		r.Basis = BasisFlat
+		factor = 2 * float64(FlatTenureMonths) / float64(FlatTenureMonths+1)
+	case monthlyPattern.MatchString(s):
+		r.Basis = BasisMonthly
+		factor = 12
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	lo, hi = round(lo*factor), round(hi*factor)
+
+	switch {
+	case len(values) == 1 && lowerOnly.MatchString(s):
+		r.MinAPR = &lo
+	case len(values) == 1 && upperOnly.MatchString(s):
+		r.MaxAPR = &hi
+	default:
+		r.MinAPR, r.MaxAPR = &lo, &hi
+	}
+	return r, nil
+}
+
+func rateNumbers(s string) []string {
+	var nums []string
+	for _, m := range percentPattern.FindAllStringSubmatch(s, -1) {
+		for _, g := range m[1:] {
+			if g != "" {
+				nums = append(nums, g)
+			}
+		}
+	}
+	if len(nums) == 0 {
+		nums = numberPattern.FindAllString(s, -1)
+	}
+	return nums
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,109 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw   string
+		min   *float64
+		max   *float64
+		basis Basis
+	}{
+		// Annual ranges.
+		{"10.5% - 24% p.a.", ptr(10.5), ptr(24), BasisAnnual},
+		{"10.5 - 24%", ptr(10.5), ptr(24), BasisAnnual},
+		{"11.25% to 22%", ptr(11.25), ptr(22), BasisAnnual},
+		{"24% – 10.5%", ptr(10.5), ptr(24), BasisAnnual},
+		{"12%", ptr(12), ptr(12), BasisAnnual},
+		{"12% for 24 months", ptr(12), ptr(12), BasisAnnual},
+
+		// Monthly rates are annualised.
+		{"starting 1.2% per month", ptr(14.4), nil, BasisMonthly},
+		{"1% - 2% p.m.", ptr(12), ptr(24), BasisMonthly},
+		{"1.5%/month", ptr(18), ptr(18), BasisMonthly},
+
+		// Flat rates are converted to an approximate reducing-balance APR.
+		{"9.99% flat", ptr(19.44), ptr(19.44), BasisFlat},
+		{"from 7% flat", ptr(13.62), nil, BasisFlat},
+
+		// One-sided rates.
+		{"up to 18%", nil, ptr(18), BasisAnnual},
+		{"Upto 36% p.a.", nil, ptr(36), BasisAnnual},
+		{"as low as 10.49%", ptr(10.49), nil, BasisAnnual},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.raw, err)
+			}
+			if got.Basis != tt.basis {
+				t.Errorf("basis = %s, want %s", got.Basis, tt.basis)
+			}
+			assertRate(t, "min", got.MinAPR, tt.min)
+			assertRate(t, "max", got.MaxAPR, tt.max)
+		})
+	}
+}
+
+func TestParseUnparseable(t *testing.T) {
+	for _, raw := range []string{"", "   ", "Contact branch", "N/A"} {
+		t.Run(raw, func(t *testing.T) {
+			got, err := Parse(raw)
+			if !errors.Is(err, ErrNoRate) {
+				t.Fatalf("Parse(%q) error = %v, want ErrNoRate", raw, err)
+			}
+			if got.Basis != BasisUnknown || got.MinAPR != nil || got.MaxAPR != nil {
+				t.Errorf("Parse(%q) = %+v, want an empty unknown range", raw, got)
+			}
+			if _, ok := got.Effective(); ok {
+				t.Error("Effective reported a rate for an unparseable string")
+			}
+		})
+	}
+}
+
+func TestEffective(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Range
+		want float64
+		ok   bool
+	}{
+		{"range uses the lower bound", Range{MinAPR: ptr(10.5), MaxAPR: ptr(24)}, 10.5, true},
+		{"up to uses the ceiling", Range{MaxAPR: ptr(18)}, 18, true},
+		{"empty", Range{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.r.Effective()
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Effective() = %v, %t, want %v, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func assertRate(t *testing.T, name string, got, want *float64) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, want %v", name, deref(got), deref(want))
+	case *got != *want:
+		t.Errorf("%s = %v, want %v", name, *got, *want)
+	}
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
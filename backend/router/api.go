@@ -10,5 +10,44 @@ func apiRouter(r *gin.Engine) {
 	api := r.Group("/api")
 	api.GET("/health", controllers.Health)
 	api.POST("/uploadcsv", controllers.UploadCSVUsers)
+	api.GET("/products", controllers.ListProducts)
+	api.POST("/products", controllers.CreateProduct)
+	api.POST("/products/bulk", controllers.BulkUpsertProducts)
+	api.GET("/products/reviews", controllers.ListProductReviews)
+	api.POST("/products/compare", controllers.CompareProducts)
+	api.GET("/products/duplicates", controllers.ListDuplicateProducts)
+	api.GET("/products/:id", controllers.GetProduct)
+	api.PUT("/products/:id", controllers.UpdateProduct)
+	api.DELETE("/products/:id", controllers.DeleteProduct)
+	api.GET("/products/:id/history", controllers.ProductHistory)
+	api.GET("/products/:id/review", controllers.GetProductReview)
+	api.POST("/products/:id/approve", controllers.ApproveProduct)
+	api.POST("/products/:id/reject", controllers.RejectProduct)
+	api.POST("/products/:id/merge", controllers.MergeProducts)
+	api.POST("/products/:id/rematch", controllers.RematchProduct)
+	api.POST("/products/:id/whatif", controllers.WhatIfProduct)
+	api.GET("/banks", controllers.ListBanks)
+	api.POST("/banks", controllers.CreateBank)
+	api.GET("/banks/merges", controllers.ListBankMerges)
+	api.POST("/banks/merges/propose", controllers.ProposeBankMerges)
+	api.POST("/banks/merges/:id/confirm", controllers.ConfirmBankMerge)
+	api.POST("/banks/merges/:id/reject", controllers.RejectBankMerge)
+	api.GET("/banks/:id", controllers.GetBank)
+	api.PUT("/banks/:id", controllers.UpdateBank)
+	api.POST("/matches/run", controllers.RunMatching)
+	api.GET("/matches/plan", controllers.MatchPlan)
+	api.POST("/matches/sweep", controllers.SweepMatches)
+	api.GET("/matches/runs", controllers.ListMatchRuns)
+	api.GET("/matches/runs/:id", controllers.GetMatchRun)
+	api.GET("/matches/runs/:id/progress", controllers.MatchRunProgress)
+	api.POST("/matches/runs/:id/cancel", controllers.CancelMatchRun)
+	api.GET("/matches/:id", controllers.GetMatch)
+	api.POST("/matches/:id/withdraw", controllers.WithdrawMatch)
+	api.POST("/eligibility/check", controllers.CheckEligibility)
+	api.GET("/eligibility/rules", controllers.ListRules)
+	api.GET("/users/:id/offers", controllers.UserOffers)
+	api.GET("/users/:id/matches", controllers.ListUserMatches)
+	api.GET("/reports/coverage", controllers.CoverageReport)
+	api.GET("/reports/fairness", controllers.FairnessReport)
 
 }
@@ -0,0 +1,53 @@
+package svc
+
+import "fmt"
+
+// band is a half-open [Min, Max) bucket used to break report figures down.
+// A zero Max means unbounded.
+type band struct {
+	Label string
+	Min   float64
+	Max   float64
+}
+
+var creditBands = []band{
+	{"below 600", 0, 600},
+	{"600-649", 600, 650},
+	{"650-699", 650, 700},
+	{"700-749", 700, 750},
+	{"750-799", 750, 800},
+	{"800+", 800, 0},
+}
+
+var incomeBands = []band{
+	{"below 25k", 0, 25000},
+	{"25k-50k", 25000, 50000},
+	{"50k-100k", 50000, 100000},
+	{"100k-200k", 100000, 200000},
+	{"200k+", 200000, 0},
+}
+
+var ageBands = []band{
+	{"18-25", 18, 26},
+	{"26-35", 26, 36},
+	{"36-45", 36, 46},
+	{"46-55", 46, 56},
+	{"56+", 56, 0},
+}
+
+func bandFor(bands []band, v float64) string {
+	for _, b := range bands {
+		if v >= b.Min && (b.Max == 0 || v < b.Max) {
+			return b.Label
+		}
+	}
+	return fmt.Sprintf("below %v", bands[0].Min)
+}
+
+func bandLabels(bands []band) []string {
+	labels := make([]string, len(bands))
+	for i, b := range bands {
+		labels[i] = b.Label
+	}
+	return labels
+}
@@ -0,0 +1,570 @@
+package svc
+
+import (
+	"errors"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BadadheVed/clickpe/banknames"
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BankSimilarityThreshold is the minimum similarity between two normalized
+// bank names for them to be proposed as the same lender.
+var BankSimilarityThreshold = 0.85
+
+var (
+	ErrBankNameConflict  = errors.New("a bank with this name already exists")
+	ErrBankMergeResolved = errors.New("bank merge has already been resolved")
+)
+
+// nameSimilarity is 1 minus the edit distance between a and b, relative to
+// the longer of the two.
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// bankIndex maps the normalized name and aliases of every bank to its ID.
+type bankIndex map[string]uuid.UUID
+
+func loadBankIndex(tx *gorm.DB) (bankIndex, error) {
+	var banks []models.Bank
+	if err := tx.Find(&banks).Error; err != nil {
+		return nil, err
+	}
+	index := bankIndex{}
+	for _, b := range banks {
+		index[banknames.Normalize(b.Name)] = b.ID
+		for _, alias := range b.Aliases {
+			index[banknames.Normalize(alias)] = b.ID
+		}
+	}
+	return index, nil
+}
+
+func (idx bankIndex) resolve(name string) *uuid.UUID {
+	id, ok := idx[banknames.Normalize(name)]
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// nearest returns the bank whose name or alias is most similar to the
+// normalized name, if any reaches BankSimilarityThreshold.
+func (idx bankIndex) nearest(normalized string) (*uuid.UUID, float64) {
+	var best *uuid.UUID
+	bestScore := 0.0
+	for key, id := range idx {
+		if s := nameSimilarity(normalized, key); s >= BankSimilarityThreshold && s > bestScore {
+			id := id
+			best, bestScore = &id, s
+		}
+	}
+	return best, bestScore
+}
+
+// linkBank sets the product's bank from its BankName, using only names and
+// aliases an admin has confirmed.
+func linkBank(tx *gorm.DB, p *models.LoanProduct) error {
+	index, err := loadBankIndex(tx)
+	if err != nil {
+		return err
+	}
+	p.BankID = index.resolve(p.BankName)
+	return nil
+}
+
+// linkUnassignedProducts links products without a bank whose name matches a
+// bank's name or alias, and returns how many were linked.
+func linkUnassignedProducts(tx *gorm.DB) (int, error) {
+	index, err := loadBankIndex(tx)
+	if err != nil {
+		return 0, err
+	}
+	var names []string
+	err = tx.Model(&models.LoanProduct{}).Where("bank_id IS NULL").Distinct().Pluck("bank_name", &names).Error
+	if err != nil {
+		return 0, err
+	}
+	linked := 0
+	for _, name := range names {
+		id := index.resolve(name)
+		if id == nil {
+			continue
+		}
+		res := tx.Model(&models.LoanProduct{}).
+			Where("bank_name = ? AND bank_id IS NULL", name).
+			UpdateColumn("bank_id", *id)
+		if res.Error != nil {
+			return linked, res.Error
+		}
+		linked += int(res.RowsAffected)
+	}
+	return linked, nil
+}
+
+type BankMergeResult struct {
+	Linked   int `json:"linked"`
+	Proposed int `json:"proposed"`
+	Cleared  int `json:"cleared"`
+}
+
+type bankNameCount struct {
+	BankName string
+	Products int
+}
+
+type bankCluster struct {
+	keys       []string
+	names      []bankNameCount
+	similarity float64
+}
+
+// ProposeBankMerges links unassigned products whose bank name already matches
+// a bank, then clusters the remaining names by normalized form and edit
+// distance and records each cluster as a pending merge for an admin to
+// confirm. Pending proposals no longer produced by the current names, because
+// their products were linked, renamed or clustered differently, are removed.
+// Proposals an admin already resolved are left alone. The clustering compares
+// every pair of names, so it runs in the background job and on request, not
+// on the request path.
+func ProposeBankMerges() (BankMergeResult, error) {
+	var result BankMergeResult
+	linked, err := linkUnassignedProducts(database.DB)
+	result.Linked = linked
+	if err != nil {
+		return result, err
+	}
+
+	var counts []bankNameCount
+	err = database.DB.Model(&models.LoanProduct{}).
+		Select("bank_name, count(*) AS products").
+		Where("bank_id IS NULL").
+		Group("bank_name").
+		Order("bank_name").
+		Scan(&counts).Error
+	if err != nil {
+		return result, err
+	}
+	index, err := loadBankIndex(database.DB)
+	if err != nil {
+		return result, err
+	}
+
+	current := []string{}
+	for _, cluster := range clusterBankNames(counts) {
+		merge := cluster.proposal()
+		current = append(current, merge.NormalizedName)
+		if id, score := index.nearest(merge.NormalizedName); id != nil {
+			merge.BankID = id
+			merge.Similarity = roundTo2(min(merge.Similarity, score))
+		}
+		res := database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "normalized_name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"canonical_name", "names", "products", "similarity", "bank_id"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Eq{Column: clause.Column{Table: "bank_merges", Name: "status"}, Value: models.BankMergePending},
+			}},
+		}).Create(&merge)
+		if res.Error != nil {
+			slog.Error("ProposeBankMerges: Upsert failed", "normalized_name", merge.NormalizedName, "error", res.Error)
+			return result, res.Error
+		}
+		result.Proposed += int(res.RowsAffected)
+	}
+
+	stale := database.DB.Where("status = ?", models.BankMergePending)
+	if len(current) > 0 {
+		stale = stale.Where("normalized_name NOT IN ?", current)
+	}
+	res := stale.Delete(&models.BankMerge{})
+	if res.Error != nil {
+		slog.Error("ProposeBankMerges: Clearing stale proposals failed", "error", res.Error)
+		return result, res.Error
+	}
+	result.Cleared = int(res.RowsAffected)
+	slog.Info("ProposeBankMerges: Done", "linked", result.Linked, "proposed", result.Proposed, "cleared", result.Cleared)
+	return result, nil
+}
+
+// clusterBankNames groups names with the same normalized form, then joins
+// groups whose normalized forms are within BankSimilarityThreshold of each
+// other (single linkage).
+func clusterBankNames(counts []bankNameCount) []*bankCluster {
+	byKey := map[string][]bankNameCount{}
+	var keys []string
+	for _, c := range counts {
+		key := banknames.Normalize(c.BankName)
+		if key == "" {
+			continue
+		}
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], c)
+	}
+	sort.Strings(keys)
+
+	var clusters []*bankCluster
+	for _, key := range keys {
+		var joined *bankCluster
+		score := 0.0
+		for _, c := range clusters {
+			for _, k := range c.keys {
+				if s := nameSimilarity(key, k); s >= BankSimilarityThreshold && s > score {
+					joined, score = c, s
+				}
+			}
+		}
+		if joined == nil {
+			joined = &bankCluster{similarity: 1}
+			clusters = append(clusters, joined)
+		} else {
+			joined.similarity = min(joined.similarity, score)
+		}
+		joined.keys = append(joined.keys, key)
+		joined.names = append(joined.names, byKey[key]...)
+	}
+	return clusters
+}
+
+// proposal builds the pending merge for a cluster. The proposed canonical
+// name is the spelling most products use, ties going to the shorter one.
+func (c *bankCluster) proposal() models.BankMerge {
+	sort.Slice(c.names, func(i, j int) bool {
+		a, b := c.names[i], c.names[j]
+		if a.Products != b.Products {
+			return a.Products > b.Products
+		}
+		if len(a.BankName) != len(b.BankName) {
+			return len(a.BankName) < len(b.BankName)
+		}
+		return a.BankName < b.BankName
+	})
+	merge := models.BankMerge{
+		CanonicalName: strings.TrimSpace(c.names[0].BankName),
+		Similarity:    roundTo2(c.similarity),
+		Status:        models.BankMergePending,
+	}
+	merge.NormalizedName = banknames.Normalize(merge.CanonicalName)
+	for _, n := range c.names {
+		merge.Names = append(merge.Names, n.BankName)
+		merge.Products += n.Products
+	}
+	sort.Strings(merge.Names)
+	return merge
+}
+
+func ListBankMerges(status models.BankMergeStatus) ([]models.BankMerge, error) {
+	merges := []models.BankMerge{}
+	q := database.DB.Order("products DESC, canonical_name")
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	err := q.Find(&merges).Error
+	return merges, err
+}
+
+// BankMergeConfirmation lets the admin rename the bank and drop names that
+// were wrongly clustered. Empty fields keep the proposal's values.
+type BankMergeConfirmation struct {
+	Name  string   `json:"name"`
+	Names []string `json:"names"`
+}
+
+// ConfirmBankMerge links the proposal's products to a bank, creating it when
+// no existing bank matches, and records the names as the bank's aliases so
+// future products with those names are linked on ingestion.
+func ConfirmBankMerge(id uuid.UUID, in BankMergeConfirmation) (*models.BankMerge, int, error) {
+	var merge models.BankMerge
+	linked := 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&merge, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if merge.Status != models.BankMergePending {
+			return ErrBankMergeResolved
+		}
+
+		names := []string(merge.Names)
+		if len(in.Names) > 0 {
+			for _, n := range in.Names {
+				if !slices.Contains(names, n) {
+					return &ValidationError{Fields: map[string]string{"names": "must be a subset of the proposed names"}}
+				}
+			}
+			names = in.Names
+		}
+
+		bank, err := mergeTargetBank(tx, &merge, strings.TrimSpace(in.Name))
+		if err != nil {
+			return err
+		}
+		for _, n := range names {
+			if n != bank.Name && !slices.Contains(bank.Aliases, n) {
+				bank.Aliases = append(bank.Aliases, n)
+			}
+		}
+		if err := tx.Save(bank).Error; err != nil {
+			return translateBankError(err)
+		}
+
+		res := tx.Model(&models.LoanProduct{}).
+			Where("bank_name IN ? AND bank_id IS NULL", names).
+			UpdateColumn("bank_id", bank.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		linked = int(res.RowsAffected)
+
+		now := time.Now()
+		merge.Status = models.BankMergeConfirmed
+		merge.BankID = &bank.ID
+		merge.Names = names
+		merge.ResolvedAt = &now
+		return tx.Save(&merge).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	slog.Info("ConfirmBankMerge: Confirmed", "merge_id", id, "bank_id", merge.BankID, "products", linked)
+	return &merge, linked, nil
+}
+
+// mergeTargetBank returns the bank a confirmed merge links to: the bank the
+// proposal matched, else an existing bank with the chosen name, else a new one.
+func mergeTargetBank(tx *gorm.DB, merge *models.BankMerge, name string) (*models.Bank, error) {
+	var bank models.Bank
+	if merge.BankID != nil {
+		err := tx.First(&bank, "id = ?", *merge.BankID).Error
+		if err == nil {
+			if name != "" && name != bank.Name {
+				bank.Aliases = append(bank.Aliases, bank.Name)
+				bank.Name = name
+			}
+			return &bank, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if name == "" {
+		name = merge.CanonicalName
+	}
+	if len(name) > 100 {
+		return nil, &ValidationError{Fields: map[string]string{"name": "must be at most 100 characters"}}
+	}
+	index, err := loadBankIndex(tx)
+	if err != nil {
+		return nil, err
+	}
+	if id := index.resolve(name); id != nil {
+		if err := tx.First(&bank, "id = ?", *id).Error; err != nil {
+			return nil, err
+		}
+		return &bank, nil
+	}
+	return &models.Bank{Name: name}, nil
+}
+
+func RejectBankMerge(id uuid.UUID) (*models.BankMerge, error) {
+	var merge models.BankMerge
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&merge, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if merge.Status != models.BankMergePending {
+			return ErrBankMergeResolved
+		}
+		now := time.Now()
+		merge.Status = models.BankMergeRejected
+		merge.ResolvedAt = &now
+		return tx.Save(&merge).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("RejectBankMerge: Rejected", "merge_id", id)
+	return &merge, nil
+}
+
+func ListBanks() ([]models.Bank, error) {
+	banks := []models.Bank{}
+	err := database.DB.Order("name").Find(&banks).Error
+	return banks, err
+}
+
+func GetBank(id uuid.UUID) (*models.Bank, error) {
+	var bank models.Bank
+	if err := database.DB.First(&bank, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &bank, nil
+}
+
+// BankInput is the editable part of a bank. An empty WebhookSecret keeps the
+// stored secret, which is never returned.
+type BankInput struct {
+	Name          string   `json:"name"`
+	Aliases       []string `json:"aliases"`
+	LogoURL       string   `json:"logo_url"`
+	ContactEmail  string   `json:"contact_email"`
+	ContactPhone  string   `json:"contact_phone"`
+	WebhookURL    string   `json:"webhook_url"`
+	WebhookSecret string   `json:"webhook_secret"`
+}
+
+func (in BankInput) Apply(b *models.Bank) {
+	b.Name = strings.TrimSpace(in.Name)
+	b.Aliases = nil
+	for _, alias := range in.Aliases {
+		if alias = strings.TrimSpace(alias); alias != "" && !slices.Contains(b.Aliases, alias) {
+			b.Aliases = append(b.Aliases, alias)
+		}
+	}
+	b.LogoURL = strings.TrimSpace(in.LogoURL)
+	b.ContactEmail = strings.TrimSpace(in.ContactEmail)
+	b.ContactPhone = strings.TrimSpace(in.ContactPhone)
+	b.WebhookURL = strings.TrimSpace(in.WebhookURL)
+	if in.WebhookSecret != "" {
+		b.WebhookSecret = in.WebhookSecret
+	}
+}
+
+func ValidateBank(b *models.Bank) error {
+	fields := map[string]string{}
+	if b.Name == "" {
+		fields["name"] = "is required"
+	} else if len(b.Name) > 100 {
+		fields["name"] = "must be at most 100 characters"
+	}
+	if b.LogoURL != "" && !isHTTPURL(b.LogoURL) {
+		fields["logo_url"] = "must be an absolute http(s) URL"
+	}
+	if b.WebhookURL != "" && !isHTTPURL(b.WebhookURL) {
+		fields["webhook_url"] = "must be an absolute http(s) URL"
+	}
+	if b.ContactEmail != "" {
+		if _, err := mail.ParseAddress(b.ContactEmail); err != nil || len(b.ContactEmail) > 255 {
+			fields["contact_email"] = "must be a valid email address"
+		}
+	}
+	if len(b.ContactPhone) > 30 {
+		fields["contact_phone"] = "must be at most 30 characters"
+	}
+	if len(b.WebhookSecret) > 255 {
+		fields["webhook_secret"] = "must be at most 255 characters"
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// CreateBank inserts a bank and links unassigned products whose bank name
+// matches its name or aliases.
+func CreateBank(in BankInput) (*models.Bank, error) {
+	var bank models.Bank
+	in.Apply(&bank)
+	if err := ValidateBank(&bank); err != nil {
+		return nil, err
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&bank).Error; err != nil {
+			return translateBankError(err)
+		}
+		_, err := linkUnassignedProducts(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("CreateBank: Created", "bank_id", bank.ID, "name", bank.Name)
+	return &bank, nil
+}
+
+// UpdateBank replaces the editable fields of a bank. Products already linked
+// stay linked; new aliases link unassigned products.
+func UpdateBank(id uuid.UUID, in BankInput) (*models.Bank, error) {
+	bank, err := GetBank(id)
+	if err != nil {
+		return nil, err
+	}
+	in.Apply(bank)
+	if err := ValidateBank(bank); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	bank.UpdatedAt = &now
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(bank).Error; err != nil {
+			return translateBankError(err)
+		}
+		_, err := linkUnassignedProducts(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("UpdateBank: Updated", "bank_id", bank.ID)
+	return bank, nil
+}
+
+func translateBankError(err error) error {
+	if t, ok := database.DB.Dialector.(gorm.ErrorTranslator); ok {
+		if errors.Is(t.Translate(err), gorm.ErrDuplicatedKey) {
+			return ErrBankNameConflict
+		}
+	}
+	return err
+}
+
+// bankNames maps bank IDs to canonical names, for reports.
+func bankNames() (map[uuid.UUID]string, error) {
+	banks, err := ListBanks()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(banks))
+	for _, b := range banks {
+		names[b.ID] = b.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,161 @@
+package svc
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"hdfc", "hdfc", 1},
+		{"", "", 1},
+		{"hdfc", "", 0},
+		{"hdfc", "hdfcc", 0.8},
+		{"kotak mahindra", "kotak mahindr", 1 - 1.0/14},
+		{"state of india", "state bank of india", 1 - 5.0/19},
+		{"yes", "axis", 0.25},
+		{"société", "societe", 1 - 2.0/7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := nameSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := nameSimilarity(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterBankNames(t *testing.T) {
+	counts := []bankNameCount{
+		{"...", 1},
+		{"Axis Bank", 4},
+		{"HDFC", 1},
+		{"HDFC Bank", 5},
+		{"HDFC Bank Ltd", 2},
+		{"ICICI Bank", 2},
+		{"ICICI Bank Limited", 2},
+		{"Kotak Mahindr Bank", 1},
+		{"Kotak Mahindra Bank", 3},
+		{"State Bank of India", 6},
+		{"State of India", 1},
+	}
+	type proposal struct {
+		Canonical  string
+		Normalized string
+		Names      []string
+		Products   int
+		Similarity float64
+	}
+	want := []proposal{
+		{"Axis Bank", "axis", []string{"Axis Bank"}, 4, 1},
+		{"HDFC Bank", "hdfc", []string{"HDFC", "HDFC Bank", "HDFC Bank Ltd"}, 8, 1},
+		// Equal counts go to the shorter spelling.
+		{"ICICI Bank", "icici", []string{"ICICI Bank", "ICICI Bank Limited"}, 4, 1},
+		{"Kotak Mahindra Bank", "kotak mahindra", []string{"Kotak Mahindr Bank", "Kotak Mahindra Bank"}, 4, 0.93},
+		// Below BankSimilarityThreshold, so proposed separately.
+		{"State Bank of India", "state bank of india", []string{"State Bank of India"}, 6, 1},
+		{"State of India", "state of india", []string{"State of India"}, 1, 1},
+	}
+
+	var got []proposal
+	for _, c := range clusterBankNames(counts) {
+		m := c.proposal()
+		if m.Status != models.BankMergePending {
+			t.Errorf("proposal %q status = %s, want pending", m.CanonicalName, m.Status)
+		}
+		got = append(got, proposal{m.CanonicalName, m.NormalizedName, m.Names, m.Products, m.Similarity})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("proposals:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+// TestProposeBankMerges checks that a run links names an admin already
+// confirmed, refreshes pending proposals, clears those no longer produced
+// and leaves resolved ones alone.
+func TestProposeBankMerges(t *testing.T) {
+	tx := testDB(t)
+	bank := models.Bank{Name: "HDFC Bank", Aliases: datatypes.JSONSlice[string]{"Housing Development Finance"}}
+	if err := tx.Create(&bank).Error; err != nil {
+		t.Fatalf("create bank: %v", err)
+	}
+	products := map[string]*models.LoanProduct{}
+	for _, name := range []string{"HDFC Bank Ltd.", "Housing Development Finance", "Kotak Mahindra Bank", "Kotak Mahindr Bank"} {
+		p := matchingtest.Product(func(p *models.LoanProduct) {
+			p.ID = uuid.Nil
+			p.BankName = name
+			p.ProductURL = "https://example.com/banks/" + uuid.NewString()
+		})
+		if err := tx.Create(p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+		products[name] = p
+	}
+	existing := []models.BankMerge{
+		{NormalizedName: "kotak mahindra", CanonicalName: "Kotak Mahindra Bank", Names: []string{"Kotak Mahindra Bank"}, Products: 1, Similarity: 1, Status: models.BankMergePending},
+		{NormalizedName: "renamed lender", CanonicalName: "Renamed Lender", Names: []string{"Renamed Lender"}, Products: 2, Similarity: 1, Status: models.BankMergePending},
+		{NormalizedName: "kotak mahindr", CanonicalName: "Kotak Mahindr Bank", Names: []string{"Kotak Mahindr Bank"}, Products: 1, Similarity: 1, Status: models.BankMergeRejected},
+	}
+	if err := tx.Create(&existing).Error; err != nil {
+		t.Fatalf("create merges: %v", err)
+	}
+
+	result, err := ProposeBankMerges()
+	if err != nil {
+		t.Fatalf("ProposeBankMerges: %v", err)
+	}
+	if result.Linked < 2 || result.Proposed < 1 || result.Cleared < 1 {
+		t.Errorf("ProposeBankMerges = %+v, want 2 linked, 1 proposed and 1 cleared", result)
+	}
+
+	t.Run("confirmed names are linked", func(t *testing.T) {
+		for _, name := range []string{"HDFC Bank Ltd.", "Housing Development Finance"} {
+			var p models.LoanProduct
+			if err := tx.First(&p, "id = ?", products[name].ID).Error; err != nil {
+				t.Fatalf("load product: %v", err)
+			}
+			if p.BankID == nil || *p.BankID != bank.ID {
+				t.Errorf("product named %q linked to %v, want %s", name, p.BankID, bank.ID)
+			}
+		}
+	})
+	t.Run("pending proposal is refreshed", func(t *testing.T) {
+		var m models.BankMerge
+		if err := tx.First(&m, "id = ?", existing[0].ID).Error; err != nil {
+			t.Fatalf("load merge: %v", err)
+		}
+		if m.Products != 2 || !reflect.DeepEqual([]string(m.Names), []string{"Kotak Mahindr Bank", "Kotak Mahindra Bank"}) || m.Similarity != 0.93 {
+			t.Errorf("proposal = %+v, want both Kotak spellings", m)
+		}
+	})
+	t.Run("stale proposal is cleared", func(t *testing.T) {
+		var n int64
+		if err := tx.Model(&models.BankMerge{}).Where("id = ?", existing[1].ID).Count(&n).Error; err != nil {
+			t.Fatalf("count merges: %v", err)
+		}
+		if n != 0 {
+			t.Error("pending proposal for a name no product uses was kept")
+		}
+	})
+	t.Run("resolved proposal is kept", func(t *testing.T) {
+		var m models.BankMerge
+		if err := tx.First(&m, "id = ?", existing[2].ID).Error; err != nil {
+			t.Fatalf("load merge: %v", err)
+		}
+		if m.Status != models.BankMergeRejected {
+			t.Errorf("rejected proposal is now %s", m.Status)
+		}
+	})
+}
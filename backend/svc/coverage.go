@@ -0,0 +1,235 @@
+package svc
+
+import (
+	"log/slog"
+	"math"
+	"sort"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// coverageSampleSize caps the user IDs listed per segment.
+const coverageSampleSize = 5
+
+// notYetMatched labels unmatched users who already qualify for a product and
+// are only waiting on a match run.
+const notYetMatched = "not_yet_matched"
+
+type NearestProduct struct {
+	ID          uuid.UUID `json:"id"`
+	ProductName string    `json:"product_name"`
+	BankName    string    `json:"bank_name"`
+	Users       int       `json:"users"`
+}
+
+type CoverageSegment struct {
+	BlockingCriterion string          `json:"blocking_criterion"`
+	Users             int             `json:"users"`
+	Share             float64         `json:"share"`
+	NearestProduct    *NearestProduct `json:"nearest_product,omitempty"`
+	AverageGap        float64         `json:"average_gap"`
+	SampleUserIDs     []uuid.UUID     `json:"sample_user_ids"`
+}
+
+type CoverageReport struct {
+	TotalUsers        int64             `json:"total_users"`
+	UnmatchedUsers    int               `json:"unmatched_users"`
+	ProductsEvaluated int               `json:"products_evaluated"`
+	Segments          []CoverageSegment `json:"segments"`
+}
+
+// BuildCoverageReport evaluates every user without an active match against every
+// product and groups them by the criterion they fail most often. Each segment
+// names the product most of its users came closest to, and the average amount
+// by which they miss that product on the blocking criterion.
+func BuildCoverageReport() (*CoverageReport, error) {
+	report := &CoverageReport{Segments: []CoverageSegment{}}
+	if err := database.DB.Model(&models.User{}).Count(&report.TotalUsers).Error; err != nil {
+		return nil, err
+	}
+
+	products, err := ListActiveProducts()
+	if err != nil {
+		return nil, err
+	}
+	report.ProductsEvaluated = len(products)
+	if len(products) == 0 {
+		return report, nil
+	}
+
+	banks, err := bankNames()
+	if err != nil {
+		return nil, err
+	}
+
+	segments := map[string]*coverageSegment{}
+	var users []models.User
+	err = database.DB.
+		Where("NOT EXISTS (SELECT 1 FROM matches m WHERE m.user_id = users.id AND m.status = ?)", models.MatchStatusActive).
+		FindInBatches(&users, matchBatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range users {
+				report.UnmatchedUsers++
+				blocker, nearest, gaps := assessCoverage(&users[i], products)
+				s, ok := segments[blocker]
+				if !ok {
+					s = newCoverageSegment(blocker)
+					segments[blocker] = s
+				}
+				s.add(users[i].ID, nearest, gaps)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		slog.Error("BuildCoverageReport: Failed", "error", err)
+		return nil, err
+	}
+
+	for _, s := range segments {
+		report.Segments = append(report.Segments, s.build(products, banks, report.UnmatchedUsers))
+	}
+	sort.Slice(report.Segments, func(i, j int) bool {
+		a, b := report.Segments[i], report.Segments[j]
+		if a.Users != b.Users {
+			return a.Users > b.Users
+		}
+		return a.BlockingCriterion < b.BlockingCriterion
+	})
+	return report, nil
+}
+
+// assessCoverage returns the criterion the user fails most often across all
+// products, the index of the nearest product and, per product, the gap on
+// that blocking criterion, or -1 where the product does not fail on it. The
+// nearest product is the one with the fewest failed checks, ties going to the
+// smallest relative shortfall.
+func assessCoverage(u *models.User, products []models.LoanProduct) (string, int, []float64) {
+	failures := map[string]int{}
+	results := make([]matching.Result, len(products))
+	nearest, nearestFailed, nearestDistance := -1, 0, 0.0
+
+	for i := range products {
+		res := matching.Evaluate(u, &products[i])
+		results[i] = res
+		failed, distance := 0, 0.0
+		for _, c := range res.Checks {
+			if c.Passed {
+				continue
+			}
+			failed++
+			failures[c.Criterion]++
+			distance += relativeGap(c, &products[i])
+		}
+		if nearest < 0 || failed < nearestFailed || (failed == nearestFailed && distance < nearestDistance) {
+			nearest, nearestFailed, nearestDistance = i, failed, distance
+		}
+	}
+
+	blocker := notYetMatched
+	for criterion, n := range failures {
+		if blocker == notYetMatched || n > failures[blocker] || (n == failures[blocker] && criterion < blocker) {
+			blocker = criterion
+		}
+	}
+
+	gaps := make([]float64, len(products))
+	for i, res := range results {
+		gaps[i] = -1
+		for _, c := range res.Checks {
+			if c.Criterion == blocker && !c.Passed {
+				gaps[i] = c.Gap
+			}
+		}
+	}
+	return blocker, nearest, gaps
+}
+
+// relativeGap scales a shortfall by the product's threshold so gaps in
+// different units can be compared. Failures without a numeric gap count as a
+// full miss.
+func relativeGap(c matching.Check, p *models.LoanProduct) float64 {
+	var threshold float64
+	switch c.Criterion {
+	case "credit_score":
+		threshold = float64(p.MinCreditScore)
+	case "monthly_income":
+		threshold = p.MinMonthlyIncome
+	case "age":
+		threshold = float64(p.Age)
+	case "foir":
+		threshold = p.MaxFOIR
+	case "loan_amount":
+		threshold = max(p.MinLoanAmount, p.MaxLoanAmount)
+	}
+	if c.Gap <= 0 || threshold <= 0 {
+		return 1
+	}
+	return c.Gap / threshold
+}
+
+type coverageSegment struct {
+	criterion string
+	users     int
+	samples   []uuid.UUID
+	nearest   map[int]int
+	gapSum    map[int]float64
+	gapCount  map[int]int
+}
+
+func newCoverageSegment(criterion string) *coverageSegment {
+	return &coverageSegment{
+		criterion: criterion,
+		nearest:   map[int]int{},
+		gapSum:    map[int]float64{},
+		gapCount:  map[int]int{},
+	}
+}
+
+func (s *coverageSegment) add(userID uuid.UUID, nearest int, gaps []float64) {
+	s.users++
+	if len(s.samples) < coverageSampleSize {
+		s.samples = append(s.samples, userID)
+	}
+	s.nearest[nearest]++
+	for i, gap := range gaps {
+		if gap >= 0 {
+			s.gapSum[i] += gap
+			s.gapCount[i]++
+		}
+	}
+}
+
+func (s *coverageSegment) build(products []models.LoanProduct, banks map[uuid.UUID]string, unmatched int) CoverageSegment {
+	out := CoverageSegment{
+		BlockingCriterion: s.criterion,
+		Users:             s.users,
+		Share:             roundTo2(float64(s.users) / float64(unmatched)),
+		SampleUserIDs:     s.samples,
+	}
+
+	best := -1
+	for i, n := range s.nearest {
+		if best < 0 || n > s.nearest[best] || (n == s.nearest[best] && i < best) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return out
+	}
+	p := &products[best]
+	out.NearestProduct = &NearestProduct{ID: p.ID, ProductName: p.ProductName, BankName: p.BankName, Users: s.nearest[best]}
+	if p.BankID != nil && banks[*p.BankID] != "" {
+		out.NearestProduct.BankName = banks[*p.BankID]
+	}
+	if s.gapCount[best] > 0 {
+		out.AverageGap = roundTo2(s.gapSum[best] / float64(s.gapCount[best]))
+	}
+	return out
+}
+
+func roundTo2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
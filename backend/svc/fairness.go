@@ -0,0 +1,270 @@
+package svc
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+)
+
+// FairnessThreshold is the largest relative shortfall in match rate or mean
+// score a group may have against the best-served group of the same dimension
+// before it is flagged. The default follows the four-fifths rule.
+var FairnessThreshold = 0.2
+
+const (
+	DimensionAgeBand          = "age_band"
+	DimensionEmploymentStatus = "employment_status"
+)
+
+type FairnessGroup struct {
+	Dimension    string  `json:"dimension"`
+	Group        string  `json:"group"`
+	Users        int     `json:"users"`
+	MatchedUsers int     `json:"matched_users"`
+	MatchRate    float64 `json:"match_rate"`
+	Matches      int     `json:"matches"`
+	ScoreMean    float64 `json:"score_mean"`
+	ScoreP25     float64 `json:"score_p25"`
+	ScoreMedian  float64 `json:"score_median"`
+	ScoreP75     float64 `json:"score_p75"`
+
+	// MatchRateRatio and ScoreRatio compare the group with the best-served
+	// group of its dimension; 1 means parity.
+	MatchRateRatio float64 `json:"match_rate_ratio"`
+	ScoreRatio     float64 `json:"score_ratio"`
+	Flagged        bool    `json:"flagged"`
+}
+
+type FairnessFlag struct {
+	Dimension string  `json:"dimension"`
+	Group     string  `json:"group"`
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+	Reference float64 `json:"reference"`
+	Reason    string  `json:"reason"`
+}
+
+type FairnessReport struct {
+	GeneratedAt      time.Time       `json:"generated_at"`
+	Threshold        float64         `json:"threshold"`
+	Users            int             `json:"users"`
+	OverallMatchRate float64         `json:"overall_match_rate"`
+	Groups           []FairnessGroup `json:"groups"`
+	Flags            []FairnessFlag  `json:"flags"`
+}
+
+// BuildFairnessReport computes match rates and active-match score
+// distributions per age band and per employment status. Groups whose match
+// rate or mean score falls more than threshold below the best group of their
+// dimension are flagged. A non-positive threshold uses FairnessThreshold.
+func BuildFairnessReport(threshold float64) (*FairnessReport, error) {
+	if threshold <= 0 {
+		threshold = FairnessThreshold
+	}
+	report := &FairnessReport{
+		GeneratedAt: time.Now(),
+		Threshold:   threshold,
+		Groups:      []FairnessGroup{},
+		Flags:       []FairnessFlag{},
+	}
+
+	dimensions := []struct {
+		name  string
+		expr  string
+		order []string
+	}{
+		{DimensionAgeBand, bandCaseSQL("COALESCE(u.age, 0)", ageBands), append(bandLabels(ageBands), "unknown")},
+		{DimensionEmploymentStatus, "COALESCE(NULLIF(lower(trim(u.employment_status)), ''), 'unknown')", nil},
+	}
+
+	for _, d := range dimensions {
+		groups, err := fairnessGroups(d.name, d.expr, d.order)
+		if err != nil {
+			slog.Error("BuildFairnessReport: Query failed", "dimension", d.name, "error", err)
+			return nil, err
+		}
+		report.Flags = append(report.Flags, flagDisparities(groups, threshold)...)
+		report.Groups = append(report.Groups, groups...)
+
+		// Every dimension partitions the same users, so any one gives the totals.
+		if report.Users == 0 {
+			matched := 0
+			for _, g := range groups {
+				report.Users += g.Users
+				matched += g.MatchedUsers
+			}
+			if report.Users > 0 {
+				report.OverallMatchRate = roundTo4(float64(matched) / float64(report.Users))
+			}
+		}
+	}
+	return report, nil
+}
+
+// bandCaseSQL renders bands as a CASE expression over a trusted column
+// expression, so age bands are the same in SQL and Go reports.
+func bandCaseSQL(expr string, bands []band) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, band := range bands {
+		if band.Max == 0 {
+			fmt.Fprintf(&b, " WHEN %s >= %v THEN '%s'", expr, band.Min, band.Label)
+		} else {
+			fmt.Fprintf(&b, " WHEN %s >= %v AND %s < %v THEN '%s'", expr, band.Min, expr, band.Max, band.Label)
+		}
+	}
+	b.WriteString(" ELSE 'unknown' END")
+	return b.String()
+}
+
+// fairnessGroups aggregates users and active matches by group. order lists
+// groups in display order; groups outside it follow alphabetically.
+func fairnessGroups(dimension, expr string, order []string) ([]FairnessGroup, error) {
+	var rates []struct {
+		Grp          string
+		Users        int
+		MatchedUsers int
+	}
+	err := database.DB.Raw(`
+SELECT `+expr+` AS grp,
+       count(*) AS users,
+       count(*) FILTER (WHERE EXISTS (
+         SELECT 1 FROM matches m WHERE m.user_id = u.id AND m.status = ?)) AS matched_users
+FROM users u
+GROUP BY 1
+ORDER BY 1`, models.MatchStatusActive).Scan(&rates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var scores []struct {
+		Grp     string
+		Matches int
+		Mean    float64
+		P25     float64
+		Median  float64
+		P75     float64
+	}
+	err = database.DB.Raw(`
+SELECT `+expr+` AS grp,
+       count(*) AS matches,
+       avg(m.score) AS mean,
+       percentile_cont(0.25) WITHIN GROUP (ORDER BY m.score) AS p25,
+       percentile_cont(0.5) WITHIN GROUP (ORDER BY m.score) AS median,
+       percentile_cont(0.75) WITHIN GROUP (ORDER BY m.score) AS p75
+FROM matches m
+JOIN users u ON u.id = m.user_id
+WHERE m.status = ?
+GROUP BY 1`, models.MatchStatusActive).Scan(&scores).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byGroup := map[string]*FairnessGroup{}
+	var seen []string
+	for _, r := range rates {
+		g := &FairnessGroup{Dimension: dimension, Group: r.Grp, Users: r.Users, MatchedUsers: r.MatchedUsers}
+		if r.Users > 0 {
+			g.MatchRate = roundTo4(float64(r.MatchedUsers) / float64(r.Users))
+		}
+		byGroup[r.Grp] = g
+		seen = append(seen, r.Grp)
+	}
+	for _, s := range scores {
+		g, ok := byGroup[s.Grp]
+		if !ok {
+			continue
+		}
+		g.Matches = s.Matches
+		g.ScoreMean = roundTo4(s.Mean)
+		g.ScoreP25 = roundTo4(s.P25)
+		g.ScoreMedian = roundTo4(s.Median)
+		g.ScoreP75 = roundTo4(s.P75)
+	}
+
+	groups := make([]FairnessGroup, 0, len(byGroup))
+	for _, label := range order {
+		if g, ok := byGroup[label]; ok {
+			groups = append(groups, *g)
+			delete(byGroup, label)
+		}
+	}
+	for _, label := range seen {
+		if g, ok := byGroup[label]; ok {
+			groups = append(groups, *g)
+		}
+	}
+	return groups, nil
+}
+
+// flagDisparities fills in each group's ratios against the best group and
+// flags those below 1 - threshold. Groups without users, and the "unknown"
+// group, are reported but never serve as the reference.
+func flagDisparities(groups []FairnessGroup, threshold float64) []FairnessFlag {
+	var bestRate, bestScore float64
+	for _, g := range groups {
+		if g.Users == 0 || g.Group == "unknown" {
+			continue
+		}
+		bestRate = max(bestRate, g.MatchRate)
+		bestScore = max(bestScore, g.ScoreMean)
+	}
+
+	var flags []FairnessFlag
+	for i := range groups {
+		g := &groups[i]
+		if g.Users == 0 {
+			continue
+		}
+		if bestRate > 0 {
+			g.MatchRateRatio = roundTo4(g.MatchRate / bestRate)
+			if g.MatchRateRatio < 1-threshold {
+				flags = append(flags, disparityFlag(g, "match_rate", g.MatchRate, bestRate, g.MatchRateRatio))
+			}
+		}
+		if bestScore > 0 && g.Matches > 0 {
+			g.ScoreRatio = roundTo4(g.ScoreMean / bestScore)
+			if g.ScoreRatio < 1-threshold {
+				flags = append(flags, disparityFlag(g, "score_mean", g.ScoreMean, bestScore, g.ScoreRatio))
+			}
+		}
+	}
+	return flags
+}
+
+func disparityFlag(g *FairnessGroup, metric string, value, reference, ratio float64) FairnessFlag {
+	g.Flagged = true
+	return FairnessFlag{
+		Dimension: g.Dimension,
+		Group:     g.Group,
+		Metric:    metric,
+		Value:     value,
+		Reference: reference,
+		Reason:    fmt.Sprintf("%s is %.0f%% of the best %s group", metric, ratio*100, g.Dimension),
+	}
+}
+
+// FairnessCSVHeader and CSVRow give the flat export of a report's groups.
+var FairnessCSVHeader = []string{
+	"dimension", "group", "users", "matched_users", "match_rate", "matches",
+	"score_mean", "score_p25", "score_median", "score_p75",
+	"match_rate_ratio", "score_ratio", "flagged",
+}
+
+func (g FairnessGroup) CSVRow() []string {
+	f := func(v float64) string { return fmt.Sprintf("%.4f", v) }
+	return []string{
+		g.Dimension, g.Group, fmt.Sprint(g.Users), fmt.Sprint(g.MatchedUsers), f(g.MatchRate), fmt.Sprint(g.Matches),
+		f(g.ScoreMean), f(g.ScoreP25), f(g.ScoreMedian), f(g.ScoreP75),
+		f(g.MatchRateRatio), f(g.ScoreRatio), fmt.Sprint(g.Flagged),
+	}
+}
+
+func roundTo4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
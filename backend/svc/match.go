@@ -0,0 +1,276 @@
+package svc
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const matchBatchSize = 1000
+
+type MatchMode string
+
+const (
+	MatchModeGo  MatchMode = "go"
+	MatchModeSQL MatchMode = "sql"
+
+	// MatchModeParallel shards users across a worker pool; see job.StartParallelMatching.
+	MatchModeParallel MatchMode = "parallel"
+)
+
+type MatchRunResult struct {
+	RunID       uuid.UUID `json:"run_id"`
+	Mode        MatchMode `json:"mode"`
+	Products    int       `json:"products"`
+	Upserted    int       `json:"upserted"`
+	Invalidated int       `json:"invalidated"`
+	DurationMs  int64     `json:"duration_ms"`
+}
+
+type RematchResult struct {
+	RunID       uuid.UUID `json:"run_id,omitempty"`
+	ProductID   uuid.UUID `json:"product_id"`
+	Upserted    int       `json:"upserted"`
+	Invalidated int       `json:"invalidated"`
+	Skipped     bool      `json:"skipped"`
+}
+
+// RunMatching recomputes matches for every product. MatchModeGo evaluates
+// products one at a time in Go; MatchModeSQL narrows the candidates of all
+// products with a single set-based join and evaluates those in Go.
+func RunMatching(mode MatchMode) (MatchRunResult, error) {
+	start := time.Now()
+	if mode != MatchModeSQL {
+		mode = MatchModeGo
+	}
+	slog.Info("RunMatching: Starting", "mode", mode)
+
+	run, err := startRun(mode, TriggerFull, nil)
+	if err != nil {
+		return MatchRunResult{Mode: mode}, err
+	}
+
+	var result MatchRunResult
+	if mode == MatchModeSQL {
+		result, err = runSetBasedMatching(run)
+	} else {
+		result, err = runGoMatching(run)
+	}
+	result.RunID = run.ID
+	result.Mode = mode
+	result.DurationMs = time.Since(start).Milliseconds()
+	finishRun(run, result.Products, result.Upserted, result.Invalidated, err)
+
+	if err != nil {
+		slog.Error("RunMatching: Failed", "mode", mode, "error", err)
+		return result, err
+	}
+	slog.Info("RunMatching: Completed", "mode", mode, "products", result.Products, "upserted", result.Upserted, "invalidated", result.Invalidated, "duration_ms", result.DurationMs)
+	return result, nil
+}
+
+func runGoMatching(run *models.MatchRun) (MatchRunResult, error) {
+	var result MatchRunResult
+	products, err := ListActiveProducts()
+	if err != nil {
+		return result, err
+	}
+
+	for i := range products {
+		res, err := rematchProduct(run, &products[i])
+		if err != nil {
+			return result, err
+		}
+		result.Products++
+		result.Upserted += res.Upserted
+		result.Invalidated += res.Invalidated
+	}
+
+	expired, err := expireInactiveProductMatches()
+	result.Invalidated += expired
+	return result, err
+}
+
+// ProductsPendingRematch returns products that were never matched or have
+// been updated since their last match run.
+func ProductsPendingRematch() ([]models.LoanProduct, error) {
+	var products []models.LoanProduct
+	err := database.DB.
+		Where("last_matched_at IS NULL OR updated_at > last_matched_at").
+		Find(&products).Error
+	return products, err
+}
+
+// RematchProductIfChanged re-evaluates a product only when its criteria hash
+// differs from the one recorded at the last match run.
+func RematchProductIfChanged(product *models.LoanProduct) (RematchResult, error) {
+	hash := matching.CriteriaHash(product)
+	if hash == product.CriteriaHash {
+		slog.Info("RematchProductIfChanged: Criteria unchanged", "product_id", product.ID)
+		return RematchResult{ProductID: product.ID, Skipped: true}, markProductMatched(product, hash)
+	}
+	return RematchProduct(product, TriggerProductChange)
+}
+
+// RematchProduct re-evaluates a single product in its own match run.
+// Candidates are narrowed with the credit score and income indexes, existing
+// matches that no longer qualify are invalidated and qualifying users are
+// upserted, so re-runs are idempotent.
+func RematchProduct(product *models.LoanProduct, trigger string) (RematchResult, error) {
+	run, err := startRun(MatchModeGo, trigger, &product.ID)
+	if err != nil {
+		return RematchResult{ProductID: product.ID}, err
+	}
+	result, err := rematchProduct(run, product)
+	finishRun(run, 1, result.Upserted, result.Invalidated, err)
+	return result, err
+}
+
+func rematchProduct(run *models.MatchRun, product *models.LoanProduct) (RematchResult, error) {
+	result := RematchResult{RunID: run.ID, ProductID: product.ID}
+	slog.Info("RematchProduct: Starting", "product_id", product.ID, "run_id", run.ID)
+
+	if product.Status == models.ProductStatusPendingReview {
+		// Existing matches were made on the approved criteria and stand until
+		// the change is approved or rejected.
+		slog.Info("RematchProduct: Product held for review, matches kept", "product_id", product.ID)
+		return result, markProductMatched(product, matching.CriteriaHash(product))
+	}
+	if !product.Active() {
+		invalidated, err := expireProductMatches(product, "product "+string(product.Status))
+		result.Invalidated = invalidated
+		if err != nil {
+			return result, err
+		}
+		slog.Info("RematchProduct: Product not active, matches expired", "product_id", product.ID, "status", product.Status, "invalidated", invalidated)
+		return result, markProductMatched(product, matching.CriteriaHash(product))
+	}
+
+	invalidated, err := invalidateStaleMatches(product)
+	if err != nil {
+		slog.Error("RematchProduct: Invalidation failed", "product_id", product.ID, "error", err)
+		return result, err
+	}
+	result.Invalidated = invalidated
+
+	upserted, err := upsertProductMatches(product, newStamp(run, product))
+	if err != nil {
+		slog.Error("RematchProduct: Match upsert failed", "product_id", product.ID, "error", err)
+		return result, err
+	}
+	result.Upserted = upserted
+
+	if err := markProductMatched(product, matching.CriteriaHash(product)); err != nil {
+		return result, err
+	}
+
+	slog.Info("RematchProduct: Completed", "product_id", product.ID, "upserted", upserted, "invalidated", invalidated)
+	return result, nil
+}
+
+// invalidateStaleMatches re-evaluates the users holding an active match on the
+// product and expires those that no longer qualify, recording the failed checks.
+// Matches on a product that is no longer active are all expired, except on a
+// product held for review, whose matches are left until the review is resolved.
+func invalidateStaleMatches(product *models.LoanProduct) (int, error) {
+	if product.Status == models.ProductStatusPendingReview {
+		return 0, nil
+	}
+	if !product.Active() {
+		return expireProductMatches(product, "product "+string(product.Status))
+	}
+	return expireFailing(product, matching.Evaluate)
+}
+
+func expireFailing(product *models.LoanProduct, evaluate func(*models.User, *models.LoanProduct) matching.Result) (int, error) {
+	failed := map[string][]uuid.UUID{}
+	var users []models.User
+
+	matched := database.DB.Model(&models.Match{}).Select("user_id").
+		Where("product_id = ? AND status = ?", product.ID, models.MatchStatusActive)
+	err := database.DB.Where("id IN (?)", matched).
+		FindInBatches(&users, matchBatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range users {
+				res := evaluate(&users[i], product)
+				if !res.Eligible {
+					reason := res.FailureReason()
+					failed[reason] = append(failed[reason], users[i].ID)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for reason, userIDs := range failed {
+		n, err := transitionMatches(
+			database.DB.Where("product_id = ? AND user_id IN ?", product.ID, userIDs),
+			models.MatchStatusExpired, reason)
+		if err != nil {
+			return expired, err
+		}
+		expired += n
+	}
+	return expired, nil
+}
+
+func upsertProductMatches(product *models.LoanProduct, stamp matchStamp) (int, error) {
+	upserted := 0
+	var users []models.User
+
+	err := database.DB.
+		Where("credit_score >= ? AND monthly_income >= ? AND COALESCE(age, 0) >= ?", product.MinCreditScore, product.MinMonthlyIncome, product.Age).
+		FindInBatches(&users, matchBatchSize, func(tx *gorm.DB, batch int) error {
+			matches := make([]models.Match, 0, len(users))
+			for i := range users {
+				res := matching.Evaluate(&users[i], product)
+				if !res.Eligible {
+					continue
+				}
+				matches = append(matches, newMatch(users[i].ID, product.ID, res, stamp))
+			}
+			if len(matches) == 0 {
+				return nil
+			}
+			n, err := upsertMatches(matches)
+			upserted += n
+			return err
+		}).Error
+	return upserted, err
+}
+
+// markProductMatched uses UpdateColumns so updated_at is not bumped, which
+// would otherwise make the product look changed again.
+func markProductMatched(product *models.LoanProduct, hash string) error {
+	now := time.Now()
+	err := database.DB.Model(product).UpdateColumns(map[string]interface{}{
+		"criteria_hash":   hash,
+		"last_matched_at": now,
+	}).Error
+	if err != nil {
+		slog.Error("markProductMatched: Update failed", "product_id", product.ID, "error", err)
+		return err
+	}
+	product.CriteriaHash = hash
+	product.LastMatchedAt = &now
+	return nil
+}
+
+// ListUserMatches returns a user's matches with their products, largest
+// estimated offer first. An empty status returns every status.
+func ListUserMatches(userID uuid.UUID, status models.MatchStatus) ([]models.Match, error) {
+	var matches []models.Match
+	q := database.DB.Preload("LoanProduct.Bank").Where("user_id = ?", userID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	err := q.Order("estimated_max_amount DESC, score DESC").Find(&matches).Error
+	return matches, err
+}
@@ -0,0 +1,180 @@
+package svc
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MatchValidity is how long a match stays active without being re-confirmed
+// by a match run.
+var MatchValidity = 30 * 24 * time.Hour
+
+type SweepResult struct {
+	WindowExpired   int `json:"window_expired"`
+	CriteriaExpired int `json:"criteria_expired"`
+}
+
+func newMatch(userID, productID uuid.UUID, res matching.Result, stamp matchStamp) models.Match {
+	now := time.Now()
+	until := now.Add(MatchValidity)
+	explanation, _ := json.Marshal(res)
+	m := models.Match{
+		UserID:          userID,
+		ProductID:       productID,
+		MatchConfidence: res.Confident(),
+		Score:           res.Score,
+		Reason:          res.Reason(),
+		Explanation:     explanation,
+		Status:          models.MatchStatusActive,
+		ValidFrom:       now,
+		ValidUntil:      &until,
+
+		RunID:            &stamp.RunID,
+		EngineVersion:    stamp.EngineVersion,
+		RulesetVersion:   stamp.RulesetVersion,
+		CriteriaSnapshot: stamp.Snapshot,
+	}
+	if res.Estimate != nil {
+		m.EstimatedMaxAmount = res.Estimate.MaxAmount
+		m.EstimatedTenureMonths = res.Estimate.TenureMonths
+	}
+	return m
+}
+
+// matchUpsertAssignments refresh the evaluation of an existing match and
+// reactivate it if it had expired or been superseded. MatchedAt and IsNotified
+// are never touched. Postgres evaluates every CASE against the old row.
+var matchUpsertAssignments = [][2]string{
+	{"reason", "excluded.reason"},
+	{"explanation", "excluded.explanation"},
+	{"estimated_max_amount", "excluded.estimated_max_amount"},
+	{"estimated_tenure_months", "excluded.estimated_tenure_months"},
+	{"score", "excluded.score"},
+	{"match_confidence", "excluded.match_confidence"},
+	{"valid_until", "excluded.valid_until"},
+	{"valid_from", "CASE WHEN matches.status = 'active' THEN matches.valid_from ELSE excluded.valid_from END"},
+	{"status_changed_at", "CASE WHEN matches.status = 'active' THEN matches.status_changed_at ELSE excluded.valid_from END"},
+	{"status_reason", "CASE WHEN matches.status = 'active' THEN matches.status_reason ELSE 'criteria met again' END"},
+	{"status", "'active'"},
+	{"run_id", "excluded.run_id"},
+	{"engine_version", "excluded.engine_version"},
+	{"ruleset_version", "excluded.ruleset_version"},
+	{"criteria_snapshot", "excluded.criteria_snapshot"},
+}
+
+// matchUpsertWhere skips withdrawn matches, which only an explicit action may
+// revive, and rows that are unchanged and still well inside their window.
+const matchUpsertWhere = `matches.status <> 'withdrawn' AND (
+     matches.status <> 'active'
+  OR matches.reason IS DISTINCT FROM excluded.reason
+  OR matches.explanation IS DISTINCT FROM excluded.explanation
+  OR matches.criteria_snapshot IS DISTINCT FROM excluded.criteria_snapshot
+  OR matches.engine_version IS DISTINCT FROM excluded.engine_version
+  OR matches.ruleset_version IS DISTINCT FROM excluded.ruleset_version
+  OR matches.score IS DISTINCT FROM excluded.score
+  OR matches.match_confidence IS DISTINCT FROM excluded.match_confidence
+  OR matches.valid_until IS NULL
+  OR matches.valid_until < excluded.valid_from + (excluded.valid_until - excluded.valid_from) / 2)`
+
+var matchUpsert = clause.OnConflict{
+	Columns:   []clause.Column{{Name: "user_id"}, {Name: "product_id"}},
+	DoUpdates: matchUpsertSet(),
+	Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: matchUpsertWhere}}},
+}
+
+func matchUpsertSet() clause.Set {
+	set := make(clause.Set, 0, len(matchUpsertAssignments))
+	for _, a := range matchUpsertAssignments {
+		set = append(set, clause.Assignment{Column: clause.Column{Name: a[0]}, Value: clause.Expr{SQL: a[1]}})
+	}
+	return set
+}
+
+func upsertMatches(matches []models.Match) (int, error) {
+	res := database.DB.Clauses(matchUpsert).Create(&matches)
+	if res.Error != nil {
+		slog.Error("upsertMatches: Upsert failed", "error", res.Error, "batch_size", len(matches))
+		return 0, res.Error
+	}
+	return int(res.RowsAffected), nil
+}
+
+// transitionMatches moves the active matches selected by scope to status and
+// records why. It returns the number of matches that changed.
+func transitionMatches(scope *gorm.DB, status models.MatchStatus, reason string) (int, error) {
+	return setMatchStatus(scope.Where("status = ?", models.MatchStatusActive), status, reason)
+}
+
+func setMatchStatus(scope *gorm.DB, status models.MatchStatus, reason string) (int, error) {
+	now := time.Now()
+	res := scope.Model(&models.Match{}).
+		UpdateColumns(map[string]interface{}{
+			"status":            status,
+			"status_reason":     reason,
+			"status_changed_at": now,
+			"valid_until":       now,
+		})
+	if res.Error != nil {
+		slog.Error("setMatchStatus: Update failed", "status", status, "error", res.Error)
+		return 0, res.Error
+	}
+	return int(res.RowsAffected), nil
+}
+
+// SweepMatches expires active matches whose validity window has elapsed or
+// whose user or product no longer satisfies the product criteria.
+func SweepMatches() (SweepResult, error) {
+	var result SweepResult
+	slog.Info("SweepMatches: Starting")
+
+	n, err := transitionMatches(database.DB.Where("valid_until < ?", time.Now()),
+		models.MatchStatusExpired, "validity window elapsed")
+	if err != nil {
+		return result, err
+	}
+	result.WindowExpired = n
+
+	var products []models.LoanProduct
+	matched := database.DB.Model(&models.Match{}).Select("product_id").Where("status = ?", models.MatchStatusActive)
+	if err := database.DB.Where("id IN (?)", matched).Find(&products).Error; err != nil {
+		return result, err
+	}
+	for i := range products {
+		n, err := invalidateStaleMatches(&products[i])
+		if err != nil {
+			return result, err
+		}
+		result.CriteriaExpired += n
+	}
+
+	slog.Info("SweepMatches: Completed", "window_expired", result.WindowExpired, "criteria_expired", result.CriteriaExpired)
+	return result, nil
+}
+
+// WithdrawMatch is an explicit withdrawal by the borrower or an admin. Match
+// runs never reactivate a withdrawn match.
+func WithdrawMatch(id uuid.UUID, reason string) (*models.Match, error) {
+	var match models.Match
+	if err := database.DB.First(&match, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = "withdrawn"
+	}
+	scope := database.DB.Where("id = ? AND status <> ?", id, models.MatchStatusWithdrawn)
+	if _, err := setMatchStatus(scope, models.MatchStatusWithdrawn, reason); err != nil {
+		return nil, err
+	}
+	if err := database.DB.First(&match, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
@@ -0,0 +1,204 @@
+package svc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// seedMatchPair creates one user and one product the user is eligible for.
+func seedMatchPair(t *testing.T, tx *gorm.DB) (*models.User, *models.LoanProduct) {
+	t.Helper()
+	user := matchingtest.User(func(u *models.User) {
+		u.ID = uuid.Nil
+		u.Email = uuid.NewString() + "@example.com"
+	})
+	product := matchingtest.Product(func(p *models.LoanProduct) {
+		p.ID = uuid.Nil
+		p.ProductURL = "https://example.com/lifecycle/" + uuid.NewString()
+	})
+	if err := tx.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := tx.Create(product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	return user, product
+}
+
+func testStamp(product *models.LoanProduct) matchStamp {
+	return newStamp(&models.MatchRun{
+		ID:             uuid.New(),
+		EngineVersion:  matching.EngineVersion,
+		RulesetVersion: matching.RulesetVersion(),
+	}, product)
+}
+
+func loadMatch(t *testing.T, tx *gorm.DB, userID, productID uuid.UUID) models.Match {
+	t.Helper()
+	var m models.Match
+	if err := tx.First(&m, "user_id = ? AND product_id = ?", userID, productID).Error; err != nil {
+		t.Fatalf("load match: %v", err)
+	}
+	return m
+}
+
+// TestUpsertMatchesIdempotent runs the same match repeatedly. Only real
+// changes touch the row, and MatchedAt and IsNotified survive every upsert.
+func TestUpsertMatchesIdempotent(t *testing.T) {
+	tx := testDB(t)
+	user, product := seedMatchPair(t, tx)
+	res := matching.Evaluate(user, product)
+	stamp := testStamp(product)
+
+	if n, err := upsertMatches([]models.Match{newMatch(user.ID, product.ID, res, stamp)}); err != nil || n != 1 {
+		t.Fatalf("first upsertMatches = %d, %v, want 1 row", n, err)
+	}
+	if err := tx.Model(&models.Match{}).Where("user_id = ?", user.ID).UpdateColumn("is_notified", true).Error; err != nil {
+		t.Fatalf("mark notified: %v", err)
+	}
+	first := loadMatch(t, tx, user.ID, product.ID)
+
+	rescored := res
+	rescored.Score = 0.42
+	setStatus := func(status models.MatchStatus) func() {
+		return func() {
+			if _, err := setMatchStatus(tx.Where("id = ?", first.ID), status, "test"); err != nil {
+				t.Fatalf("set status: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		prepare    func()
+		res        matching.Result
+		wantRows   int
+		wantStatus models.MatchStatus
+		wantScore  float64
+		wantReason string
+	}{
+		{name: "repeat run is a no-op", res: res, wantStatus: models.MatchStatusActive, wantScore: first.Score},
+		{name: "changed score refreshes", res: rescored, wantRows: 1, wantStatus: models.MatchStatusActive, wantScore: 0.42},
+		{name: "expired match reactivates", prepare: setStatus(models.MatchStatusExpired), res: rescored, wantRows: 1,
+			wantStatus: models.MatchStatusActive, wantScore: 0.42, wantReason: "criteria met again"},
+		{name: "superseded match reactivates", prepare: setStatus(models.MatchStatusSuperseded), res: res, wantRows: 1,
+			wantStatus: models.MatchStatusActive, wantScore: first.Score, wantReason: "criteria met again"},
+		{name: "withdrawn match stays withdrawn", prepare: setStatus(models.MatchStatusWithdrawn), res: rescored,
+			wantStatus: models.MatchStatusWithdrawn, wantScore: first.Score, wantReason: "test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+			n, err := upsertMatches([]models.Match{newMatch(user.ID, product.ID, tt.res, stamp)})
+			if err != nil {
+				t.Fatalf("upsertMatches: %v", err)
+			}
+			if n != tt.wantRows {
+				t.Errorf("upsertMatches changed %d rows, want %d", n, tt.wantRows)
+			}
+
+			got := loadMatch(t, tx, user.ID, product.ID)
+			if got.ID != first.ID || !got.MatchedAt.Equal(first.MatchedAt) || !got.IsNotified {
+				t.Errorf("match identity changed: id %s matched_at %s notified %t, want %s, %s, true",
+					got.ID, got.MatchedAt, got.IsNotified, first.ID, first.MatchedAt)
+			}
+			if got.Status != tt.wantStatus || got.Score != tt.wantScore || got.StatusReason != tt.wantReason {
+				t.Errorf("match is %s (%q) with score %v, want %s (%q) with score %v",
+					got.Status, got.StatusReason, got.Score, tt.wantStatus, tt.wantReason, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestNewMatchValidityWindow(t *testing.T) {
+	user, product := matchingtest.User(), matchingtest.Product()
+	res := matching.Evaluate(user, product)
+	defer func(v time.Duration) { MatchValidity = v }(MatchValidity)
+
+	for _, validity := range []time.Duration{time.Hour, 30 * 24 * time.Hour} {
+		t.Run(validity.String(), func(t *testing.T) {
+			MatchValidity = validity
+			before := time.Now()
+			m := newMatch(user.ID, product.ID, res, testStamp(product))
+			if m.Status != models.MatchStatusActive {
+				t.Errorf("status = %s, want active", m.Status)
+			}
+			if m.ValidFrom.Before(before) || m.ValidFrom.After(time.Now()) {
+				t.Errorf("valid_from = %s, want the time of the call", m.ValidFrom)
+			}
+			if m.ValidUntil == nil || !m.ValidUntil.Equal(m.ValidFrom.Add(validity)) {
+				t.Errorf("valid_until = %v, want valid_from + %s", m.ValidUntil, validity)
+			}
+		})
+	}
+}
+
+// TestSweepMatches seeds one match per way a match can end and checks which
+// ones the sweeper expires, and why.
+func TestSweepMatches(t *testing.T) {
+	tx := testDB(t)
+	eligible, product := seedMatchPair(t, tx)
+	_, inactive := seedMatchPair(t, tx)
+	if err := tx.Model(inactive).UpdateColumn("status", models.ProductStatusInactive).Error; err != nil {
+		t.Fatalf("deactivate product: %v", err)
+	}
+	failing := matchingtest.User(func(u *models.User) {
+		u.ID = uuid.Nil
+		u.Email = uuid.NewString() + "@example.com"
+		u.CreditScore = 600
+	})
+	if err := tx.Create(failing).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	other, _ := seedMatchPair(t, tx)
+
+	now := time.Now()
+	future, past := now.Add(time.Hour), now.Add(-time.Hour)
+	matches := []models.Match{
+		{UserID: eligible.ID, ProductID: product.ID, Status: models.MatchStatusActive, ValidUntil: &future},
+		{UserID: other.ID, ProductID: product.ID, Status: models.MatchStatusActive, ValidUntil: &past},
+		{UserID: failing.ID, ProductID: product.ID, Status: models.MatchStatusActive, ValidUntil: &future},
+		{UserID: eligible.ID, ProductID: inactive.ID, Status: models.MatchStatusActive, ValidUntil: &future},
+		{UserID: other.ID, ProductID: inactive.ID, Status: models.MatchStatusWithdrawn, StatusReason: "withdrawn", ValidUntil: &past},
+	}
+	if err := tx.Create(&matches).Error; err != nil {
+		t.Fatalf("create matches: %v", err)
+	}
+
+	result, err := SweepMatches()
+	if err != nil {
+		t.Fatalf("SweepMatches: %v", err)
+	}
+	if want := (SweepResult{WindowExpired: 1, CriteriaExpired: 2}); result != want {
+		t.Errorf("SweepMatches = %+v, want %+v", result, want)
+	}
+
+	tests := []struct {
+		name       string
+		match      models.Match
+		wantStatus models.MatchStatus
+		wantReason string
+	}{
+		{"inside its window", matches[0], models.MatchStatusActive, ""},
+		{"window elapsed", matches[1], models.MatchStatusExpired, "validity window elapsed"},
+		{"user no longer eligible", matches[2], models.MatchStatusExpired, matching.Evaluate(failing, product).FailureReason()},
+		{"product inactive", matches[3], models.MatchStatusExpired, "product inactive"},
+		{"withdrawn", matches[4], models.MatchStatusWithdrawn, "withdrawn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := loadMatch(t, tx, tt.match.UserID, tt.match.ProductID)
+			if got.Status != tt.wantStatus || got.StatusReason != tt.wantReason {
+				t.Errorf("match is %s (%q), want %s (%q)", got.Status, got.StatusReason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
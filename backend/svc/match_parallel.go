@@ -0,0 +1,248 @@
+package svc
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+// shardPageSize is the number of users a shard worker loads per page.
+const shardPageSize = 500
+
+// UserShard is a half-open [From, To) range of user IDs. A nil To means the
+// shard runs to the end of the ID space.
+type UserShard struct {
+	Index int        `json:"index"`
+	From  uuid.UUID  `json:"from"`
+	To    *uuid.UUID `json:"to,omitempty"`
+}
+
+// UserShards splits the user ID space into n contiguous ranges. User IDs are
+// random UUIDs, so equal ranges hold roughly equal numbers of users.
+func UserShards(n int) []UserShard {
+	if n < 1 {
+		n = 1
+	}
+	if n > 1<<16 {
+		n = 1 << 16
+	}
+	shards := make([]UserShard, n)
+	for i := range shards {
+		shards[i] = UserShard{Index: i, From: shardBoundary(i, n)}
+		if i < n-1 {
+			to := shardBoundary(i+1, n)
+			shards[i].To = &to
+		}
+	}
+	return shards
+}
+
+// shardBoundary is the first UUID of shard i, split on the two leading bytes.
+func shardBoundary(i, n int) uuid.UUID {
+	var id uuid.UUID
+	binary.BigEndian.PutUint16(id[:2], uint16(i*(1<<16)/n))
+	return id
+}
+
+// ProductCatalog is the snapshot of active products a parallel run evaluates users
+// against. Workers share it read-only, so products edited mid-run are picked
+// up by the next run or the product watcher.
+type ProductCatalog struct {
+	Products []models.LoanProduct
+	stamps   []matchStamp
+}
+
+func loadCatalog(run *models.MatchRun) (*ProductCatalog, error) {
+	products, err := ListActiveProducts()
+	if err != nil {
+		return nil, err
+	}
+	catalog := &ProductCatalog{
+		Products: products,
+		stamps:   make([]matchStamp, len(products)),
+	}
+	for i := range products {
+		catalog.stamps[i] = newStamp(run, &products[i])
+	}
+	return catalog, nil
+}
+
+// StartParallelRun records a parallel match run and snapshots the catalog.
+// It also returns the number of users, for progress reporting.
+func StartParallelRun() (*models.MatchRun, *ProductCatalog, int64, error) {
+	run, err := startRun(MatchModeParallel, TriggerFull, nil)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	catalog, err := loadCatalog(run)
+	if err == nil {
+		var users int64
+		err = database.DB.Model(&models.User{}).Count(&users).Error
+		if err == nil {
+			return run, catalog, users, nil
+		}
+	}
+	finishRun(run, 0, 0, 0, err)
+	return nil, nil, 0, err
+}
+
+// FinishParallelRun records the outcome of a parallel run. Products are only
+// marked matched, and matches on products outside the catalog expired, when
+// every shard completed.
+func FinishParallelRun(run *models.MatchRun, catalog *ProductCatalog, upserted, invalidated int, runErr error) {
+	if runErr == nil {
+		var expired int
+		expired, runErr = expireInactiveProductMatches()
+		invalidated += expired
+	}
+	if runErr == nil {
+		for i := range catalog.Products {
+			p := &catalog.Products[i]
+			if err := markProductMatched(p, matching.CriteriaHash(p)); err != nil {
+				runErr = err
+				break
+			}
+		}
+	}
+	finishRun(run, len(catalog.Products), upserted, invalidated, runErr)
+}
+
+type ShardResult struct {
+	Shard       int
+	Users       int
+	Upserted    int
+	Invalidated int
+	Err         error
+}
+
+// PageProgress is reported after every page a shard worker finishes.
+type PageProgress func(users, upserted, invalidated int)
+
+// MatchShard streams the shard's users a page at a time by keyset pagination,
+// evaluates each page against the catalog, upserts qualifying matches and
+// expires active matches that no longer qualify. It stops between pages once
+// ctx is cancelled.
+func MatchShard(ctx context.Context, catalog *ProductCatalog, shard UserShard, progress PageProgress) ShardResult {
+	result := ShardResult{Shard: shard.Index}
+	after := shard.From
+	first := true
+
+	for {
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			return result
+		}
+
+		q := database.DB.WithContext(ctx)
+		if first {
+			q = q.Where("id >= ?", after)
+		} else {
+			q = q.Where("id > ?", after)
+		}
+		if shard.To != nil {
+			q = q.Where("id < ?", *shard.To)
+		}
+		var users []models.User
+		if err := q.Order("id").Limit(shardPageSize).Find(&users).Error; err != nil {
+			result.Err = err
+			return result
+		}
+		if len(users) == 0 {
+			return result
+		}
+
+		upserted, invalidated, err := matchPage(ctx, catalog, users)
+		result.Users += len(users)
+		result.Upserted += upserted
+		result.Invalidated += invalidated
+		if progress != nil {
+			progress(len(users), upserted, invalidated)
+		}
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		after, first = users[len(users)-1].ID, false
+		if len(users) < shardPageSize {
+			return result
+		}
+	}
+}
+
+func matchPage(ctx context.Context, catalog *ProductCatalog, users []models.User) (int, int, error) {
+	userIDs := make([]uuid.UUID, len(users))
+	for i := range users {
+		userIDs[i] = users[i].ID
+	}
+
+	var active []struct {
+		UserID    uuid.UUID
+		ProductID uuid.UUID
+	}
+	err := database.DB.WithContext(ctx).Model(&models.Match{}).
+		Select("user_id, product_id").
+		Where("status = ? AND user_id IN ?", models.MatchStatusActive, userIDs).
+		Scan(&active).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	held := make(map[[2]uuid.UUID]bool, len(active))
+	for _, m := range active {
+		held[[2]uuid.UUID{m.UserID, m.ProductID}] = true
+	}
+
+	// Failures are only needed for matches the user currently holds.
+	type failure struct {
+		product int
+		reason  string
+	}
+	failed := map[failure][]uuid.UUID{}
+	matches := make([]models.Match, 0, len(users))
+	upserted := 0
+
+	for i := range users {
+		u := &users[i]
+		for j := range catalog.Products {
+			p := &catalog.Products[j]
+			res := matching.Evaluate(u, p)
+			if res.Eligible {
+				matches = append(matches, newMatch(u.ID, p.ID, res, catalog.stamps[j]))
+			} else if held[[2]uuid.UUID{u.ID, p.ID}] {
+				key := failure{j, res.FailureReason()}
+				failed[key] = append(failed[key], u.ID)
+			}
+		}
+		if len(matches) >= matchBatchSize {
+			n, err := upsertMatches(matches)
+			upserted += n
+			if err != nil {
+				return upserted, 0, err
+			}
+			matches = matches[:0]
+		}
+	}
+	if len(matches) > 0 {
+		n, err := upsertMatches(matches)
+		upserted += n
+		if err != nil {
+			return upserted, 0, err
+		}
+	}
+
+	invalidated := 0
+	for key, ids := range failed {
+		n, err := transitionMatches(
+			database.DB.Where("product_id = ? AND user_id IN ?", catalog.Products[key.product].ID, ids),
+			models.MatchStatusExpired, key.reason)
+		if err != nil {
+			return upserted, invalidated, err
+		}
+		invalidated += n
+	}
+	return upserted, invalidated, nil
+}
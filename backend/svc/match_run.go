@@ -0,0 +1,95 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+const (
+	TriggerFull          = "full"
+	TriggerManual        = "manual"
+	TriggerProductChange = "product_change"
+	TriggerProductMerge  = "product_merge"
+)
+
+// matchStamp is the provenance written onto every match a run touches.
+type matchStamp struct {
+	RunID          uuid.UUID
+	EngineVersion  string
+	RulesetVersion string
+	Snapshot       []byte
+}
+
+func newStamp(run *models.MatchRun, product *models.LoanProduct) matchStamp {
+	snapshot, _ := json.Marshal(matching.Snapshot(product))
+	return matchStamp{
+		RunID:          run.ID,
+		EngineVersion:  run.EngineVersion,
+		RulesetVersion: run.RulesetVersion,
+		Snapshot:       snapshot,
+	}
+}
+
+func startRun(mode MatchMode, trigger string, productID *uuid.UUID) (*models.MatchRun, error) {
+	run := &models.MatchRun{
+		ID:             uuid.New(),
+		Mode:           string(mode),
+		Trigger:        trigger,
+		ProductID:      productID,
+		EngineVersion:  matching.EngineVersion,
+		RulesetVersion: matching.RulesetVersion(),
+		Status:         models.MatchRunRunning,
+	}
+	if err := database.DB.Create(run).Error; err != nil {
+		slog.Error("startRun: Failed to record match run", "error", err)
+		return nil, err
+	}
+	slog.Info("Match run started", "run_id", run.ID, "mode", mode, "trigger", trigger, "ruleset_version", run.RulesetVersion)
+	return run, nil
+}
+
+func finishRun(run *models.MatchRun, products, upserted, invalidated int, runErr error) {
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Products, run.Upserted, run.Invalidated = products, upserted, invalidated
+	run.Status = models.MatchRunCompleted
+	if errors.Is(runErr, context.Canceled) {
+		run.Status = models.MatchRunCancelled
+	} else if runErr != nil {
+		run.Status = models.MatchRunFailed
+		run.Error = runErr.Error()
+	}
+	if err := database.DB.Save(run).Error; err != nil {
+		slog.Error("finishRun: Failed to record match run", "run_id", run.ID, "error", err)
+	}
+}
+
+func GetMatchRun(id uuid.UUID) (*models.MatchRun, error) {
+	var run models.MatchRun
+	if err := database.DB.First(&run, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func ListMatchRuns(limit int) ([]models.MatchRun, error) {
+	var runs []models.MatchRun
+	err := database.DB.Order("started_at DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+func GetMatch(id uuid.UUID) (*models.Match, error) {
+	var match models.Match
+	if err := database.DB.First(&match, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
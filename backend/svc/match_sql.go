@@ -0,0 +1,283 @@
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+// setBasedSelect applies the structured criteria as a join between users and
+// loan_products and returns each candidate user with the product it may match.
+// It is only a prefilter: every candidate is evaluated by matching.Evaluate,
+// which computes the score, reason, estimate and explanation exactly as the Go
+// mode does.
+const setBasedSelect = `
+SELECT p.id AS candidate_product_id, u.*
+FROM loan_products p
+JOIN users u
+  ON u.credit_score >= p.min_credit_score
+ AND u.monthly_income >= p.min_monthly_income
+ AND COALESCE(u.age, 0) >= COALESCE(p.age, 0)
+ AND (COALESCE(u.requested_loan_type, '') = '' OR COALESCE(p.loan_type, '') = '' OR u.requested_loan_type = p.loan_type)
+ AND (COALESCE(u.requested_amount, 0) = 0
+      OR (u.requested_amount >= p.min_loan_amount AND (p.max_loan_amount = 0 OR u.requested_amount <= p.max_loan_amount)))
+WHERE p.id IN @ids`
+
+// setBasedCandidatePage reads setBasedSelect a page at a time by keyset on
+// (user, product), so matches can be upserted between pages.
+const setBasedCandidatePage = `
+SELECT * FROM (` + setBasedSelect + `) c
+WHERE (c.id, c.candidate_product_id) > (@after_user, @after_product)
+ORDER BY c.id, c.candidate_product_id
+LIMIT @limit`
+
+// setBasedStalePage returns, a page at a time, the active matches that fail a
+// structured criterion, with their users. matching.Evaluate supplies the
+// failure reason recorded when they are expired.
+const setBasedStalePage = `
+SELECT m.id AS match_id, p.id AS candidate_product_id, u.*
+FROM matches m
+JOIN users u ON u.id = m.user_id
+JOIN loan_products p ON p.id = m.product_id
+WHERE p.id IN @ids
+  AND m.status = 'active'
+  AND m.id > @after_match
+  AND (u.credit_score < p.min_credit_score
+    OR u.monthly_income < p.min_monthly_income
+    OR COALESCE(u.age, 0) < COALESCE(p.age, 0)
+    OR (COALESCE(u.requested_loan_type, '') <> '' AND COALESCE(p.loan_type, '') <> '' AND u.requested_loan_type <> p.loan_type)
+    OR (COALESCE(u.requested_amount, 0) > 0
+        AND (u.requested_amount < p.min_loan_amount OR (p.max_loan_amount > 0 AND u.requested_amount > p.max_loan_amount))))
+ORDER BY m.id
+LIMIT @limit`
+
+// setBasedRow is a user selected by the set-based prefilter together with
+// the product, and for stale matches the match, it was selected for.
+type setBasedRow struct {
+	MatchID            uuid.UUID
+	CandidateProductID uuid.UUID
+	models.User
+}
+
+// userIndexes are the indexes the set-based join is expected to use.
+var userIndexes = []string{"idx_users_credit_score", "idx_users_income"}
+
+type MatchPlan struct {
+	Indexes       []string        `json:"indexes"`
+	UsesUserIndex bool            `json:"uses_user_index"`
+	Plan          json.RawMessage `json:"plan"`
+}
+
+func runSetBasedMatching(run *models.MatchRun) (MatchRunResult, error) {
+	var result MatchRunResult
+
+	structured, residual, err := splitProductsByResidual()
+	if err != nil {
+		return result, err
+	}
+	result.Products = len(structured) + len(residual)
+
+	if len(structured) > 0 {
+		byID := make(map[uuid.UUID]*models.LoanProduct, len(structured))
+		stamps := make(map[uuid.UUID]matchStamp, len(structured))
+		for i := range structured {
+			byID[structured[i].ID] = &structured[i]
+			stamps[structured[i].ID] = newStamp(run, &structured[i])
+		}
+		ids := productIDs(structured)
+
+		invalidated, err := expireSetBased(ids, byID)
+		result.Invalidated = invalidated
+		if err != nil {
+			return result, err
+		}
+		slog.Info("runSetBasedMatching: Structured invalidation done", "invalidated", result.Invalidated)
+
+		upserted, err := upsertSetBased(ids, byID, stamps)
+		result.Upserted = upserted
+		if err != nil {
+			return result, err
+		}
+		slog.Info("runSetBasedMatching: Structured upsert done", "upserted", result.Upserted)
+
+		for i := range structured {
+			if err := markProductMatched(&structured[i], matching.CriteriaHash(&structured[i])); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	// Residual products still get their candidates narrowed by the structured
+	// criteria in SQL; only the residual rules run in Go.
+	for i := range residual {
+		res, err := rematchProduct(run, &residual[i])
+		if err != nil {
+			return result, err
+		}
+		result.Upserted += res.Upserted
+		result.Invalidated += res.Invalidated
+	}
+
+	expired, err := expireInactiveProductMatches()
+	result.Invalidated += expired
+	return result, err
+}
+
+// expireSetBased expires the active matches the prefilter finds failing a
+// structured criterion, grouped by the failure reason matching.Evaluate gives.
+func expireSetBased(ids []uuid.UUID, byID map[uuid.UUID]*models.LoanProduct) (int, error) {
+	expired := 0
+	after := uuid.Nil
+	for {
+		var rows []setBasedRow
+		err := database.DB.Raw(setBasedStalePage, map[string]interface{}{
+			"ids": ids, "after_match": after, "limit": matchBatchSize,
+		}).Scan(&rows).Error
+		if err != nil {
+			return expired, err
+		}
+		if len(rows) == 0 {
+			return expired, nil
+		}
+
+		failed := map[string][]uuid.UUID{}
+		for i := range rows {
+			res := matching.Evaluate(&rows[i].User, byID[rows[i].CandidateProductID])
+			if !res.Eligible {
+				reason := res.FailureReason()
+				failed[reason] = append(failed[reason], rows[i].MatchID)
+			}
+		}
+		for reason, matchIDs := range failed {
+			n, err := transitionMatches(database.DB.Where("id IN ?", matchIDs), models.MatchStatusExpired, reason)
+			if err != nil {
+				return expired, err
+			}
+			expired += n
+		}
+
+		if len(rows) < matchBatchSize {
+			return expired, nil
+		}
+		after = rows[len(rows)-1].MatchID
+	}
+}
+
+// upsertSetBased evaluates the prefilter's candidates in Go and upserts the
+// eligible ones a page at a time.
+func upsertSetBased(ids []uuid.UUID, byID map[uuid.UUID]*models.LoanProduct, stamps map[uuid.UUID]matchStamp) (int, error) {
+	upserted := 0
+	afterUser, afterProduct := uuid.Nil, uuid.Nil
+	for {
+		var rows []setBasedRow
+		err := database.DB.Raw(setBasedCandidatePage, map[string]interface{}{
+			"ids": ids, "after_user": afterUser, "after_product": afterProduct, "limit": matchBatchSize,
+		}).Scan(&rows).Error
+		if err != nil {
+			return upserted, err
+		}
+		if len(rows) == 0 {
+			return upserted, nil
+		}
+
+		matches := make([]models.Match, 0, len(rows))
+		for i := range rows {
+			productID := rows[i].CandidateProductID
+			res := matching.Evaluate(&rows[i].User, byID[productID])
+			if res.Eligible {
+				matches = append(matches, newMatch(rows[i].ID, productID, res, stamps[productID]))
+			}
+		}
+		if len(matches) > 0 {
+			n, err := upsertMatches(matches)
+			upserted += n
+			if err != nil {
+				return upserted, err
+			}
+		}
+
+		if len(rows) < matchBatchSize {
+			return upserted, nil
+		}
+		last := rows[len(rows)-1]
+		afterUser, afterProduct = last.ID, last.CandidateProductID
+	}
+}
+
+func splitProductsByResidual() (structured, residual []models.LoanProduct, err error) {
+	products, err := ListActiveProducts()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range products {
+		if matching.HasResidual(&p) {
+			residual = append(residual, p)
+		} else {
+			structured = append(structured, p)
+		}
+	}
+	return structured, residual, nil
+}
+
+func productIDs(products []models.LoanProduct) []uuid.UUID {
+	ids := make([]uuid.UUID, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+	return ids
+}
+
+// ExplainSetBasedMatching returns the planner output for the set-based join
+// and whether it reaches the users table through the credit score or income index.
+func ExplainSetBasedMatching() (MatchPlan, error) {
+	var plan MatchPlan
+	structured, _, err := splitProductsByResidual()
+	if err != nil {
+		return plan, err
+	}
+
+	var raw string
+	err = database.DB.Raw("EXPLAIN (FORMAT JSON) "+setBasedSelect, map[string]interface{}{"ids": productIDs(structured)}).
+		Row().Scan(&raw)
+	if err != nil {
+		return plan, err
+	}
+
+	var nodes []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(raw), &nodes); err != nil {
+		return plan, fmt.Errorf("unexpected EXPLAIN output: %w", err)
+	}
+
+	plan.Plan = json.RawMessage(raw)
+	for _, n := range nodes {
+		n.Plan.collectIndexes(&plan.Indexes)
+	}
+	for _, idx := range plan.Indexes {
+		if slices.Contains(userIndexes, idx) {
+			plan.UsesUserIndex = true
+		}
+	}
+	return plan, nil
+}
+
+type planNode struct {
+	IndexName string     `json:"Index Name"`
+	Plans     []planNode `json:"Plans"`
+}
+
+func (n planNode) collectIndexes(out *[]string) {
+	if n.IndexName != "" && !slices.Contains(*out, n.IndexName) {
+		*out = append(*out, n.IndexName)
+	}
+	for _, child := range n.Plans {
+		child.collectIndexes(out)
+	}
+}
@@ -0,0 +1,294 @@
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The tests below need a Postgres database. Point TEST_DATABASE_URL at a
+// scratch database to run them; every test works inside a transaction that
+// is rolled back.
+var (
+	testConnOnce sync.Once
+	testConn     *gorm.DB
+	testConnErr  error
+)
+
+// testDB points database.DB at a transaction on TEST_DATABASE_URL for the
+// duration of the test and skips the test when the variable is not set.
+func testDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+	testConnOnce.Do(func() {
+		testConn, testConnErr = gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if testConnErr == nil {
+			testConnErr = database.Migrate(testConn)
+		}
+	})
+	if testConnErr != nil {
+		tb.Fatalf("test database: %v", testConnErr)
+	}
+
+	tx := testConn.Begin()
+	if tx.Error != nil {
+		tb.Fatalf("begin: %v", tx.Error)
+	}
+	prev := database.DB
+	database.DB = tx
+	tb.Cleanup(func() {
+		tx.Rollback()
+		database.DB = prev
+	})
+	return tx
+}
+
+// seedEdgeProducts creates structured-only products at the edges of the
+// score and estimate formulas.
+func seedEdgeProducts(tb testing.TB, tx *gorm.DB) []models.LoanProduct {
+	tb.Helper()
+	products := []models.LoanProduct{
+		{ProductName: "Ordinary", MinCreditScore: 650, MinMonthlyIncome: 15000, Age: 21},
+		// A credit span of zero or less scores full credit headroom.
+		{ProductName: "Top credit band", MinCreditScore: 900, Age: 18},
+		{ProductName: "Above the credit scale", MinCreditScore: 950, MinMonthlyIncome: 10000.01, Age: 18},
+		// 171/300 = 0.57 halves to 0.285, which float64 rounds down.
+		{ProductName: "Rounding boundary", MinCreditScore: 600, MinMonthlyIncome: 20000, Age: 18},
+		{
+			ProductName: "Bounded personal", LoanType: models.LoanTypePersonal,
+			MinCreditScore: 700, MinMonthlyIncome: 33333.33, Age: 25,
+			MinLoanAmount: 100000, MaxLoanAmount: 750000, MinTenureMonths: 12, MaxTenureMonths: 48,
+			RawCriteria: datatypes.JSON(`{"income_multiplier": 2.5}`),
+		},
+		{
+			ProductName: "Long minimum tenure", MinCreditScore: 650, MinMonthlyIncome: 15000, Age: 21,
+			MinTenureMonths: 72, RawCriteria: datatypes.JSON(`{"income_multiplier": -1}`),
+		},
+	}
+	for i := range products {
+		p := &products[i]
+		p.BankName = "Equivalence Bank"
+		p.InterestRate = "10.5% - 24% p.a."
+		p.ProductURL = fmt.Sprintf("https://example.com/equivalence/%s", uuid.NewString())
+		if err := tx.Create(p).Error; err != nil {
+			tb.Fatalf("create product: %v", err)
+		}
+	}
+	return products
+}
+
+// seedEdgeUsers creates a grid of users around the product thresholds.
+func seedEdgeUsers(tb testing.TB, tx *gorm.DB) []models.User {
+	tb.Helper()
+	type request struct {
+		loanType models.LoanType
+		amount   float64
+		tenure   int
+	}
+	requests := []request{{}, {models.LoanTypePersonal, 300000, 24}, {models.LoanTypeHome, 50000, 0}, {models.LoanTypePersonal, 2000000, 120}}
+
+	var users []models.User
+	for _, credit := range []int{599, 600, 650, 700, 771, 900, 960} {
+		for _, income := range []float64{9999.99, 10000.01, 15000, 20000, 33333.33, 45000.5, 250000} {
+			for _, age := range []int{0, 21, 40} {
+				for _, r := range requests {
+					users = append(users, models.User{
+						Name:                  "Equivalence User",
+						Email:                 uuid.NewString() + "@example.com",
+						Age:                   age,
+						MonthlyIncome:         income,
+						CreditScore:           credit,
+						EmploymentStatus:      "salaried",
+						RequestedLoanType:     r.loanType,
+						RequestedAmount:       r.amount,
+						RequestedTenureMonths: r.tenure,
+					})
+				}
+			}
+		}
+	}
+	if err := tx.CreateInBatches(&users, 500).Error; err != nil {
+		tb.Fatalf("create users: %v", err)
+	}
+	return users
+}
+
+// comparableMatch is a match without the columns that differ between any two
+// runs, such as IDs and timestamps.
+type comparableMatch struct {
+	UserID                uuid.UUID
+	ProductID             uuid.UUID
+	Status                models.MatchStatus
+	StatusReason          string
+	MatchConfidence       bool
+	Score                 float64
+	Reason                string
+	EstimatedMaxAmount    float64
+	EstimatedTenureMonths int
+	Explanation           any
+	CriteriaSnapshot      any
+}
+
+func loadComparableMatches(tb testing.TB, productIDs []uuid.UUID) []comparableMatch {
+	tb.Helper()
+	var matches []models.Match
+	err := database.DB.Where("product_id IN ?", productIDs).Order("product_id, user_id").Find(&matches).Error
+	if err != nil {
+		tb.Fatalf("load matches: %v", err)
+	}
+	out := make([]comparableMatch, len(matches))
+	for i, m := range matches {
+		out[i] = comparableMatch{
+			UserID:                m.UserID,
+			ProductID:             m.ProductID,
+			Status:                m.Status,
+			StatusReason:          m.StatusReason,
+			MatchConfidence:       m.MatchConfidence,
+			Score:                 m.Score,
+			Reason:                m.Reason,
+			EstimatedMaxAmount:    m.EstimatedMaxAmount,
+			EstimatedTenureMonths: m.EstimatedTenureMonths,
+			Explanation:           decodeJSON(tb, m.Explanation),
+			CriteriaSnapshot:      decodeJSON(tb, m.CriteriaSnapshot),
+		}
+	}
+	return out
+}
+
+func decodeJSON(tb testing.TB, raw datatypes.JSON) any {
+	tb.Helper()
+	if len(raw) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		tb.Fatalf("decode %s: %v", raw, err)
+	}
+	return v
+}
+
+// TestSetBasedMatchingMatchesGo runs both full matching modes on the same data
+// and requires identical match rows, including matches each mode expires.
+func TestSetBasedMatchingMatchesGo(t *testing.T) {
+	tx := testDB(t)
+	products := seedEdgeProducts(t, tx)
+	users := seedEdgeUsers(t, tx)
+	ids := productIDs(products)
+
+	// Active matches that no longer qualify must be expired with the same reason.
+	var stale []models.Match
+	for _, p := range products {
+		for _, u := range users {
+			if u.CreditScore < p.MinCreditScore && u.RequestedAmount == 0 {
+				stale = append(stale, models.Match{UserID: u.ID, ProductID: p.ID, Reason: "matched before the criteria changed"})
+			}
+		}
+	}
+	if err := tx.CreateInBatches(&stale, 500).Error; err != nil {
+		t.Fatalf("create stale matches: %v", err)
+	}
+
+	if err := tx.SavePoint("seeded").Error; err != nil {
+		t.Fatalf("savepoint: %v", err)
+	}
+	results := map[MatchMode][]comparableMatch{}
+	for _, mode := range []MatchMode{MatchModeGo, MatchModeSQL} {
+		if _, err := RunMatching(mode); err != nil {
+			t.Fatalf("RunMatching(%s): %v", mode, err)
+		}
+		results[mode] = loadComparableMatches(t, ids)
+		if err := tx.RollbackTo("seeded").Error; err != nil {
+			t.Fatalf("rollback to savepoint: %v", err)
+		}
+	}
+
+	goRows, sqlRows := results[MatchModeGo], results[MatchModeSQL]
+	if len(goRows) != len(sqlRows) {
+		t.Fatalf("go mode wrote %d matches, sql mode %d", len(goRows), len(sqlRows))
+	}
+	active := 0
+	for i := range goRows {
+		if goRows[i].Status == models.MatchStatusActive {
+			active++
+		}
+		if !reflect.DeepEqual(goRows[i], sqlRows[i]) {
+			t.Errorf("match %d differs:\n go: %+v\nsql: %+v", i, goRows[i], sqlRows[i])
+		}
+	}
+	if active == 0 || active == len(goRows) {
+		t.Fatalf("%d of %d matches active; the seed data does not exercise both paths", active, len(goRows))
+	}
+}
+
+func BenchmarkRunMatching(b *testing.B) {
+	tx := testDB(b)
+	seedEdgeProducts(b, tx)
+	seedEdgeUsers(b, tx)
+	if err := tx.SavePoint("seeded").Error; err != nil {
+		b.Fatalf("savepoint: %v", err)
+	}
+
+	for _, mode := range []MatchMode{MatchModeGo, MatchModeSQL} {
+		b.Run(string(mode), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := RunMatching(mode); err != nil {
+					b.Fatalf("RunMatching(%s): %v", mode, err)
+				}
+				b.StopTimer()
+				if err := tx.RollbackTo("seeded").Error; err != nil {
+					b.Fatalf("rollback to savepoint: %v", err)
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+// TestFullRunExpiresMatchesOnInactiveProducts checks that full runs, which
+// only visit active products, still expire matches on the other products.
+func TestFullRunExpiresMatchesOnInactiveProducts(t *testing.T) {
+	tx := testDB(t)
+	products := seedEdgeProducts(t, tx)
+	users := seedEdgeUsers(t, tx)
+	inactive := &products[0]
+	if err := tx.Model(inactive).UpdateColumn("status", models.ProductStatusInactive).Error; err != nil {
+		t.Fatalf("deactivate product: %v", err)
+	}
+	held := models.Match{UserID: users[0].ID, ProductID: inactive.ID, Reason: "matched while active"}
+	if err := tx.Create(&held).Error; err != nil {
+		t.Fatalf("create match: %v", err)
+	}
+	if err := tx.SavePoint("seeded").Error; err != nil {
+		t.Fatalf("savepoint: %v", err)
+	}
+
+	for _, mode := range []MatchMode{MatchModeGo, MatchModeSQL} {
+		t.Run(string(mode), func(t *testing.T) {
+			defer tx.RollbackTo("seeded")
+			if _, err := RunMatching(mode); err != nil {
+				t.Fatalf("RunMatching: %v", err)
+			}
+			var got models.Match
+			if err := tx.First(&got, "id = ?", held.ID).Error; err != nil {
+				t.Fatalf("load match: %v", err)
+			}
+			if got.Status != models.MatchStatusExpired || got.StatusReason != "product inactive" {
+				t.Errorf("match status = %s (%q), want expired (%q)", got.Status, got.StatusReason, "product inactive")
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/rates"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func GetProduct(id uuid.UUID) (*models.LoanProduct, error) {
+	var product models.LoanProduct
+	if err := database.DB.First(&product, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func ListProducts() ([]models.LoanProduct, error) {
+	var products []models.LoanProduct
+	err := database.DB.Find(&products).Error
+	return products, err
+}
+
+// SyncProductRates parses InterestRate for products written outside the API
+// (the crawler writes to Postgres directly), so BeforeSave never ran for them.
+func SyncProductRates() (int, error) {
+	var products []models.LoanProduct
+	err := database.DB.
+		Where("rate_basis IS NULL OR rate_basis = '' OR last_matched_at IS NULL OR updated_at > last_matched_at").
+		Find(&products).Error
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for i := range products {
+		p := &products[i]
+		if !p.ParseInterestRate() {
+			continue
+		}
+		err := database.DB.Model(p).UpdateColumns(map[string]interface{}{
+			"min_apr":    p.MinAPR,
+			"max_apr":    p.MaxAPR,
+			"rate_basis": p.RateBasis,
+		}).Error
+		if err != nil {
+			slog.Error("SyncProductRates: Update failed", "product_id", p.ID, "error", err)
+			return synced, err
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// ErrProductURLConflict is returned when another product already uses the
+// product URL.
+var ErrProductURLConflict = errors.New("a product with this product_url already exists")
+
+// ValidationError maps JSON field names to what is wrong with them.
+type ValidationError struct {
+	Fields map[string]string `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for field, msg := range e.Fields {
+		parts = append(parts, field+": "+msg)
+	}
+	sort.Strings(parts)
+	return "invalid product: " + strings.Join(parts, "; ")
+}
+
+// ProductInput is the editable part of a loan product. IDs, timestamps and
+// matching bookkeeping are never taken from the request.
+type ProductInput struct {
+	BankName         string          `json:"bank_name"`
+	ProductName      string          `json:"product_name"`
+	LoanType         models.LoanType `json:"loan_type"`
+	InterestRate     string          `json:"interest_rate"`
+	MinCreditScore   int             `json:"min_credit_score"`
+	MinMonthlyIncome float64         `json:"min_monthly_income"`
+	MaxFOIR          float64         `json:"max_foir"`
+	MinLoanAmount    float64         `json:"min_loan_amount"`
+	MaxLoanAmount    float64         `json:"max_loan_amount"`
+	MinTenureMonths  int             `json:"min_tenure_months"`
+	MaxTenureMonths  int             `json:"max_tenure_months"`
+	RawCriteria      json.RawMessage `json:"raw_criteria"`
+
+	ProcessingFeePercent float64 `json:"processing_fee_percent"`
+	ProcessingFeeFlat    float64 `json:"processing_fee_flat"`
+	ProductURL           string  `json:"product_url"`
+	Age                  int     `json:"age"`
+
+	ServiceablePincodes []string `json:"serviceable_pincodes"`
+	ServiceableCities   []string `json:"serviceable_cities"`
+	CityTiers           []int    `json:"city_tiers"`
+}
+
+// Apply copies the input onto p.
+func (in ProductInput) Apply(p *models.LoanProduct) {
+	p.BankName = strings.TrimSpace(in.BankName)
+	p.ProductName = strings.TrimSpace(in.ProductName)
+	p.LoanType = models.LoanType(strings.ToLower(strings.TrimSpace(string(in.LoanType))))
+	p.InterestRate = strings.TrimSpace(in.InterestRate)
+	p.MinCreditScore = in.MinCreditScore
+	p.MinMonthlyIncome = in.MinMonthlyIncome
+	p.MaxFOIR = in.MaxFOIR
+	p.MinLoanAmount = in.MinLoanAmount
+	p.MaxLoanAmount = in.MaxLoanAmount
+	p.MinTenureMonths = in.MinTenureMonths
+	p.MaxTenureMonths = in.MaxTenureMonths
+	p.ProcessingFeePercent = in.ProcessingFeePercent
+	p.ProcessingFeeFlat = in.ProcessingFeeFlat
+	p.RawCriteria = nil
+	if len(in.RawCriteria) > 0 && string(in.RawCriteria) != "null" {
+		p.RawCriteria = datatypes.JSON(in.RawCriteria)
+	}
+	p.ProductURL = strings.TrimSpace(in.ProductURL)
+	p.Age = in.Age
+	p.ServiceablePincodes = in.ServiceablePincodes
+	p.ServiceableCities = in.ServiceableCities
+	p.CityTiers = in.CityTiers
+}
+
+// ValidateProduct checks the fields the matching engine relies on. Zero
+// thresholds mean "no requirement" and are always accepted.
+func ValidateProduct(p *models.LoanProduct) error {
+	fields := map[string]string{}
+
+	if p.BankName == "" {
+		fields["bank_name"] = "is required"
+	} else if len(p.BankName) > 100 {
+		fields["bank_name"] = "must be at most 100 characters"
+	}
+	if p.ProductName == "" {
+		fields["product_name"] = "is required"
+	} else if len(p.ProductName) > 255 {
+		fields["product_name"] = "must be at most 255 characters"
+	}
+	if u, err := url.Parse(p.ProductURL); p.ProductURL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields["product_url"] = "must be an absolute http(s) URL"
+	}
+	if p.InterestRate != "" {
+		if len(p.InterestRate) > 50 {
+			fields["interest_rate"] = "must be at most 50 characters"
+		} else if _, err := rates.Parse(p.InterestRate); err != nil {
+			fields["interest_rate"] = err.Error()
+		}
+	}
+	if p.MinCreditScore != 0 && (p.MinCreditScore < 300 || p.MinCreditScore > matching.MaxCreditScore) {
+		fields["min_credit_score"] = fmt.Sprintf("must be 0 or between 300 and %d", matching.MaxCreditScore)
+	}
+	if p.MinMonthlyIncome < 0 {
+		fields["min_monthly_income"] = "must not be negative"
+	}
+	if p.Age != 0 && (p.Age < 18 || p.Age > 100) {
+		fields["age"] = "must be 0 or between 18 and 100"
+	}
+	if p.MaxFOIR < 0 || p.MaxFOIR > 1 {
+		fields["max_foir"] = "must be a ratio between 0 and 1"
+	}
+	if p.MinLoanAmount < 0 {
+		fields["min_loan_amount"] = "must not be negative"
+	}
+	if p.MaxLoanAmount < 0 {
+		fields["max_loan_amount"] = "must not be negative"
+	} else if p.MaxLoanAmount > 0 && p.MaxLoanAmount < p.MinLoanAmount {
+		fields["max_loan_amount"] = "must not be below min_loan_amount"
+	}
+	if !models.ValidLoanType(p.LoanType) {
+		fields["loan_type"] = fmt.Sprintf("must be one of %v", models.LoanTypes)
+	}
+	if p.MinTenureMonths < 0 || p.MinTenureMonths > 480 {
+		fields["min_tenure_months"] = "must be between 0 and 480"
+	}
+	if p.MaxTenureMonths < 0 || p.MaxTenureMonths > 480 {
+		fields["max_tenure_months"] = "must be between 0 and 480"
+	} else if p.MaxTenureMonths > 0 && p.MaxTenureMonths < p.MinTenureMonths {
+		fields["max_tenure_months"] = "must not be below min_tenure_months"
+	}
+	if p.ProcessingFeePercent < 0 || p.ProcessingFeePercent > 100 {
+		fields["processing_fee_percent"] = "must be between 0 and 100"
+	}
+	if p.ProcessingFeeFlat < 0 {
+		fields["processing_fee_flat"] = "must not be negative"
+	}
+	for _, pincode := range p.ServiceablePincodes {
+		if !isPincodePrefix(pincode) {
+			fields["serviceable_pincodes"] = fmt.Sprintf("%q is not a pincode or pincode prefix", pincode)
+			break
+		}
+	}
+	for _, tier := range p.CityTiers {
+		if tier < 1 || tier > 3 {
+			fields["city_tiers"] = "tiers must be 1, 2 or 3"
+			break
+		}
+	}
+	if msg := validateRawCriteria(p); msg != "" {
+		fields["raw_criteria"] = msg
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func isPincodePrefix(s string) bool {
+	if len(s) == 0 || len(s) > 6 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateRawCriteria rejects criteria the engine would fail closed on:
+// malformed JSON and rules that are not registered.
+func validateRawCriteria(p *models.LoanProduct) string {
+	if err := matching.ValidateRawCriteria(json.RawMessage(p.RawCriteria)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// CreateProduct validates and inserts a product and records its first
+// criteria version. The product watcher picks it up for matching because it
+// has never been matched.
+func CreateProduct(in ProductInput, source models.ProductChangeSource) (*models.LoanProduct, error) {
+	var product models.LoanProduct
+	in.Apply(&product)
+	if err := ValidateProduct(&product); err != nil {
+		return nil, err
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := linkBank(tx, &product); err != nil {
+			return err
+		}
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		_, err := recordProductChange(tx, &product, source)
+		return err
+	})
+	if err := translateError(err); err != nil {
+		return nil, err
+	}
+	slog.Info("CreateProduct: Created", "product_id", product.ID, "bank_name", product.BankName, "source", source)
+	return &product, nil
+}
+
+// UpdateProduct replaces the editable fields of a product. UpdatedAt only
+// moves when something changed, so an identical PUT does not trigger a
+// rematch. Criteria changes are versioned in the same transaction.
+func UpdateProduct(id uuid.UUID, in ProductInput, source models.ProductChangeSource) (*models.LoanProduct, error) {
+	product, err := GetProduct(id)
+	if err != nil {
+		return nil, err
+	}
+	before := productFingerprint(product)
+	in.Apply(product)
+	if err := ValidateProduct(product); err != nil {
+		return nil, err
+	}
+	if productFingerprint(product) == before {
+		return product, nil
+	}
+
+	now := time.Now()
+	product.UpdatedAt = &now
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := linkBank(tx, product); err != nil {
+			return err
+		}
+		if err := tx.Save(product).Error; err != nil {
+			return err
+		}
+		_, err := recordProductChange(tx, product, source)
+		return err
+	})
+	if err := translateError(err); err != nil {
+		return nil, err
+	}
+	slog.Info("UpdateProduct: Updated", "product_id", product.ID, "source", source)
+	return product, nil
+}
+
+// DeleteProduct removes a product; its matches are removed by the foreign key.
+func DeleteProduct(id uuid.UUID) error {
+	res := database.DB.Delete(&models.LoanProduct{}, "id = ?", id)
+	if res.Error != nil {
+		slog.Error("DeleteProduct: Delete failed", "product_id", id, "error", res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	slog.Info("DeleteProduct: Deleted", "product_id", id)
+	return nil
+}
+
+// productFingerprint covers every editable field, to detect no-op updates.
+// RawCriteria is compared canonically since Postgres reorders jsonb keys.
+func productFingerprint(p *models.LoanProduct) string {
+	raw, _ := json.Marshal(ProductInput{
+		BankName:             p.BankName,
+		ProductName:          p.ProductName,
+		LoanType:             p.LoanType,
+		InterestRate:         p.InterestRate,
+		MinCreditScore:       p.MinCreditScore,
+		MinMonthlyIncome:     p.MinMonthlyIncome,
+		MaxFOIR:              p.MaxFOIR,
+		MinLoanAmount:        p.MinLoanAmount,
+		MaxLoanAmount:        p.MaxLoanAmount,
+		MinTenureMonths:      p.MinTenureMonths,
+		MaxTenureMonths:      p.MaxTenureMonths,
+		RawCriteria:          canonicalJSON(json.RawMessage(p.RawCriteria)),
+		ProcessingFeePercent: p.ProcessingFeePercent,
+		ProcessingFeeFlat:    p.ProcessingFeeFlat,
+		ProductURL:           p.ProductURL,
+		Age:                  p.Age,
+		ServiceablePincodes:  p.ServiceablePincodes,
+		ServiceableCities:    p.ServiceableCities,
+		CityTiers:            p.CityTiers,
+	})
+	return string(raw)
+}
+
+// translateError maps driver errors to gorm's portable errors and a unique
+// violation on product_url to ErrProductURLConflict.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if t, ok := database.DB.Dialector.(gorm.ErrorTranslator); ok {
+		if errors.Is(t.Translate(err), gorm.ErrDuplicatedKey) {
+			return ErrProductURLConflict
+		}
+	}
+	return err
+}
+
+type UpsertOutcome string
+
+const (
+	UpsertCreated   UpsertOutcome = "created"
+	UpsertUpdated   UpsertOutcome = "updated"
+	UpsertUnchanged UpsertOutcome = "unchanged"
+	UpsertRejected  UpsertOutcome = "rejected"
+)
+
+// UpsertProductByURL creates the product or updates the one with the same
+// ProductURL. An identical entry is reported unchanged and not written. Every
+// accepted crawler entry marks the product seen, reactivating it if needed.
+func UpsertProductByURL(in ProductInput, source models.ProductChangeSource) (*models.LoanProduct, UpsertOutcome, error) {
+	product, outcome, err := upsertProductByURL(in, source, true)
+	if err != nil || source != models.ProductSourceCrawler {
+		return product, outcome, err
+	}
+	if err := markProductSeen(product); err != nil {
+		return product, outcome, err
+	}
+	return product, outcome, nil
+}
+
+func upsertProductByURL(in ProductInput, source models.ProductChangeSource, retry bool) (*models.LoanProduct, UpsertOutcome, error) {
+	var candidate models.LoanProduct
+	in.Apply(&candidate)
+	if err := ValidateProduct(&candidate); err != nil {
+		return nil, UpsertRejected, err
+	}
+
+	var existing models.LoanProduct
+	err := database.DB.Where("product_url = ?", candidate.ProductURL).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		product, err := CreateProduct(in, source)
+		if retry && errors.Is(err, ErrProductURLConflict) {
+			// Created concurrently by another request; update it instead.
+			return upsertProductByURL(in, source, false)
+		}
+		if err != nil {
+			return nil, UpsertRejected, err
+		}
+		return product, UpsertCreated, nil
+	}
+	if err != nil {
+		return nil, UpsertRejected, err
+	}
+
+	if productFingerprint(&existing) == productFingerprint(&candidate) {
+		return &existing, UpsertUnchanged, nil
+	}
+	product, err := UpdateProduct(existing.ID, in, source)
+	if err != nil {
+		return nil, UpsertRejected, err
+	}
+	return product, UpsertUpdated, nil
+}
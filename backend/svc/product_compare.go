@@ -0,0 +1,227 @@
+package svc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/rates"
+	"github.com/google/uuid"
+)
+
+const (
+	MinCompareProducts = 2
+	MaxCompareProducts = 5
+)
+
+// CompareRequest names the products to compare and the loan to price them
+// for. Without an amount or tenure the user's requested loan is used when a
+// user is given; a missing tenure defaults to matching.DefaultTenureMonths.
+type CompareRequest struct {
+	ProductIDs   []uuid.UUID `json:"product_ids"`
+	Amount       float64     `json:"amount"`
+	TenureMonths int         `json:"tenure_months"`
+	UserID       *uuid.UUID  `json:"user_id"`
+}
+
+// LoanCost prices the loan at one APR. TotalCost adds the processing fee to
+// the interest, so products with different fee structures compare directly.
+type LoanCost struct {
+	APR           float64 `json:"apr"`
+	EMI           float64 `json:"emi"`
+	TotalInterest float64 `json:"total_interest"`
+	TotalCost     float64 `json:"total_cost"`
+}
+
+type ComparedProduct struct {
+	ProductID   uuid.UUID            `json:"product_id"`
+	BankName    string               `json:"bank_name"`
+	ProductName string               `json:"product_name"`
+	LoanType    models.LoanType      `json:"loan_type"`
+	ProductURL  string               `json:"product_url"`
+	Status      models.ProductStatus `json:"status"`
+
+	InterestRate string      `json:"interest_rate"`
+	APR          rates.Range `json:"apr"`
+
+	ProcessingFeePercent float64  `json:"processing_fee_percent"`
+	ProcessingFeeFlat    float64  `json:"processing_fee_flat"`
+	ProcessingFee        *float64 `json:"processing_fee,omitempty"`
+
+	// Best and Worst price the loan at the lowest and highest advertised APR.
+	Best  *LoanCost `json:"best_case,omitempty"`
+	Worst *LoanCost `json:"worst_case,omitempty"`
+
+	Criteria    matching.CriteriaSnapshot `json:"criteria"`
+	Eligibility *matching.Result          `json:"eligibility,omitempty"`
+	Notes       []string                  `json:"notes,omitempty"`
+}
+
+type Comparison struct {
+	Amount       float64           `json:"amount"`
+	TenureMonths int               `json:"tenure_months"`
+	UserID       *uuid.UUID        `json:"user_id,omitempty"`
+	Products     []ComparedProduct `json:"products"`
+
+	// LowestEMI and LowestTotalCost point at the cheapest product in the
+	// best case, when the loan could be priced.
+	LowestEMI       *uuid.UUID `json:"lowest_emi,omitempty"`
+	LowestTotalCost *uuid.UUID `json:"lowest_total_cost,omitempty"`
+}
+
+// CompareProducts builds a side-by-side view of 2 to 5 products, in the order
+// requested.
+func CompareProducts(req CompareRequest) (*Comparison, error) {
+	if err := validateCompareRequest(req); err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	if req.UserID != nil {
+		u, err := GetUser(*req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		user = u
+		if req.Amount == 0 {
+			req.Amount = u.RequestedAmount
+		}
+		if req.TenureMonths == 0 {
+			req.TenureMonths = u.RequestedTenureMonths
+		}
+	}
+	if req.TenureMonths == 0 {
+		req.TenureMonths = matching.DefaultTenureMonths
+	}
+
+	var products []models.LoanProduct
+	if err := database.DB.Preload("Bank").Where("id IN ?", req.ProductIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.LoanProduct, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+	var missing []string
+	for _, id := range req.ProductIDs {
+		if byID[id] == nil {
+			missing = append(missing, id.String())
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &ValidationError{Fields: map[string]string{"product_ids": "unknown products: " + strings.Join(missing, ", ")}}
+	}
+
+	cmp := &Comparison{Amount: req.Amount, TenureMonths: req.TenureMonths, UserID: req.UserID}
+	lowestEMI, lowestCost := math.Inf(1), math.Inf(1)
+	for _, id := range req.ProductIDs {
+		row := compareProduct(byID[id], req.Amount, req.TenureMonths, user)
+		if row.Best != nil {
+			if row.Best.EMI < lowestEMI {
+				lowestEMI, cmp.LowestEMI = row.Best.EMI, &row.ProductID
+			}
+			if row.Best.TotalCost < lowestCost {
+				lowestCost, cmp.LowestTotalCost = row.Best.TotalCost, &row.ProductID
+			}
+		}
+		cmp.Products = append(cmp.Products, row)
+	}
+	return cmp, nil
+}
+
+func validateCompareRequest(req CompareRequest) error {
+	fields := map[string]string{}
+	if n := len(req.ProductIDs); n < MinCompareProducts || n > MaxCompareProducts {
+		fields["product_ids"] = fmt.Sprintf("must list between %d and %d products", MinCompareProducts, MaxCompareProducts)
+	} else {
+		seen := map[uuid.UUID]bool{}
+		for _, id := range req.ProductIDs {
+			if seen[id] {
+				fields["product_ids"] = "must not repeat a product"
+				break
+			}
+			seen[id] = true
+		}
+	}
+	if req.Amount < 0 {
+		fields["amount"] = "must not be negative"
+	}
+	if req.TenureMonths < 0 || req.TenureMonths > 480 {
+		fields["tenure_months"] = "must be between 0 and 480"
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func compareProduct(p *models.LoanProduct, amount float64, tenure int, user *models.User) ComparedProduct {
+	row := ComparedProduct{
+		ProductID:            p.ID,
+		BankName:             p.DisplayBankName(),
+		ProductName:          p.ProductName,
+		LoanType:             p.LoanType,
+		ProductURL:           p.ProductURL,
+		Status:               p.Status,
+		InterestRate:         p.InterestRate,
+		APR:                  p.RateRange(),
+		ProcessingFeePercent: p.ProcessingFeePercent,
+		ProcessingFeeFlat:    p.ProcessingFeeFlat,
+		Criteria:             matching.Snapshot(p),
+	}
+
+	if !p.Active() {
+		row.Notes = append(row.Notes, fmt.Sprintf("product is %s and not currently offered", p.Status))
+	}
+	if amount > 0 {
+		fee := roundTo2(p.ProcessingFee(amount))
+		row.ProcessingFee = &fee
+		if p.MinLoanAmount > 0 && amount < p.MinLoanAmount {
+			row.Notes = append(row.Notes, fmt.Sprintf("amount is below the product minimum of %.2f", p.MinLoanAmount))
+		}
+		if p.MaxLoanAmount > 0 && amount > p.MaxLoanAmount {
+			row.Notes = append(row.Notes, fmt.Sprintf("amount is above the product maximum of %.2f", p.MaxLoanAmount))
+		}
+	}
+	if p.MinTenureMonths > 0 && tenure < p.MinTenureMonths {
+		row.Notes = append(row.Notes, fmt.Sprintf("tenure is below the product minimum of %d months", p.MinTenureMonths))
+	}
+	if p.MaxTenureMonths > 0 && tenure > p.MaxTenureMonths {
+		row.Notes = append(row.Notes, fmt.Sprintf("tenure is above the product maximum of %d months", p.MaxTenureMonths))
+	}
+
+	switch {
+	case p.MinAPR == nil && p.MaxAPR == nil:
+		row.Notes = append(row.Notes, fmt.Sprintf("interest rate %q could not be parsed", p.InterestRate))
+	case amount > 0:
+		low, high := p.MinAPR, p.MaxAPR
+		if low == nil {
+			low = high
+		}
+		if high == nil {
+			high = low
+		}
+		row.Best = loanCost(p, amount, tenure, *low)
+		row.Worst = loanCost(p, amount, tenure, *high)
+	}
+
+	if user != nil {
+		res := matching.Evaluate(user, p)
+		row.Eligibility = &res
+	}
+	return row
+}
+
+func loanCost(p *models.LoanProduct, amount float64, tenure int, apr float64) *LoanCost {
+	emi := matching.EMI(amount, apr, tenure)
+	interest := roundTo2(math.Max(0, emi*float64(tenure)-amount))
+	return &LoanCost{
+		APR:           apr,
+		EMI:           emi,
+		TotalInterest: interest,
+		TotalCost:     roundTo2(interest + p.ProcessingFee(amount)),
+	}
+}
@@ -0,0 +1,172 @@
+package svc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+func TestCompareProductPricing(t *testing.T) {
+	withFees := func(p *models.LoanProduct) { p.ProcessingFeePercent, p.ProcessingFeeFlat = 2, 500 }
+	withRate := func(rate string) func(*models.LoanProduct) {
+		return func(p *models.LoanProduct) {
+			p.InterestRate = rate
+			p.ParseInterestRate()
+		}
+	}
+	fee := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		product *models.LoanProduct
+		amount  float64
+		tenure  int
+		fee     *float64
+		best    *LoanCost
+		worst   *LoanCost
+		notes   []string
+	}{
+		{name: "range priced at both ends", product: matchingtest.Product(withFees), amount: 300000, tenure: 24, fee: fee(6500),
+			best:  &LoanCost{APR: 10.5, EMI: 13912.81, TotalInterest: 33907.44, TotalCost: 40407.44},
+			worst: &LoanCost{APR: 24, EMI: 15861.33, TotalInterest: 80671.92, TotalCost: 87171.92}},
+		{name: "single rate", product: matchingtest.Product(withRate("12% p.a.")), amount: 300000, tenure: 24, fee: fee(0),
+			best:  &LoanCost{APR: 12, EMI: 14122.04, TotalInterest: 38928.96, TotalCost: 38928.96},
+			worst: &LoanCost{APR: 12, EMI: 14122.04, TotalInterest: 38928.96, TotalCost: 38928.96}},
+		{name: "no amount", product: matchingtest.Product(withFees), tenure: 24},
+		{name: "rate not parsed", product: matchingtest.Product(withRate("contact branch")), amount: 300000, tenure: 24, fee: fee(0),
+			notes: []string{`interest rate "contact branch" could not be parsed`}},
+		{name: "outside the product ranges", amount: 50000, tenure: 6, fee: fee(0),
+			product: matchingtest.Product(withRate("12% p.a."), func(p *models.LoanProduct) {
+				p.MinLoanAmount, p.MinTenureMonths = 100000, 12
+			}),
+			best:  &LoanCost{APR: 12, EMI: 8627.42, TotalInterest: 1764.52, TotalCost: 1764.52},
+			worst: &LoanCost{APR: 12, EMI: 8627.42, TotalInterest: 1764.52, TotalCost: 1764.52},
+			notes: []string{"amount is below the product minimum of 100000.00", "tenure is below the product minimum of 12 months"}},
+		{name: "above the product maxima", amount: 600000, tenure: 60,
+			product: matchingtest.Product(withRate("contact branch"), func(p *models.LoanProduct) {
+				p.MaxLoanAmount, p.MaxTenureMonths, p.Status = 500000, 48, models.ProductStatusInactive
+			}),
+			fee: fee(0),
+			notes: []string{
+				"product is inactive and not currently offered",
+				"amount is above the product maximum of 500000.00",
+				"tenure is above the product maximum of 48 months",
+				`interest rate "contact branch" could not be parsed`,
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := compareProduct(tt.product, tt.amount, tt.tenure, nil)
+			if !reflect.DeepEqual(row.ProcessingFee, tt.fee) {
+				t.Errorf("processing fee = %v, want %v", ptrValue(row.ProcessingFee), ptrValue(tt.fee))
+			}
+			if !reflect.DeepEqual(row.Best, tt.best) || !reflect.DeepEqual(row.Worst, tt.worst) {
+				t.Errorf("best, worst = %+v, %+v, want %+v, %+v", row.Best, row.Worst, tt.best, tt.worst)
+			}
+			if !reflect.DeepEqual(row.Notes, tt.notes) {
+				t.Errorf("notes = %q, want %q", row.Notes, tt.notes)
+			}
+			if row.Eligibility != nil {
+				t.Error("eligibility evaluated without a user")
+			}
+		})
+	}
+
+	row := compareProduct(matchingtest.Product(), 300000, 24, matchingtest.User())
+	if row.Eligibility == nil || !row.Eligibility.Eligible {
+		t.Errorf("eligibility = %+v, want the user evaluated as eligible", row.Eligibility)
+	}
+}
+
+func ptrValue(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func TestValidateCompareRequest(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	tests := []struct {
+		name   string
+		req    CompareRequest
+		fields []string
+	}{
+		{name: "two products", req: CompareRequest{ProductIDs: []uuid.UUID{a, b}, Amount: 300000, TenureMonths: 24}},
+		{name: "five products", req: CompareRequest{ProductIDs: []uuid.UUID{a, b, uuid.New(), uuid.New(), uuid.New()}}},
+		{name: "one product", req: CompareRequest{ProductIDs: []uuid.UUID{a}}, fields: []string{"product_ids"}},
+		{name: "six products", req: CompareRequest{ProductIDs: []uuid.UUID{a, b, uuid.New(), uuid.New(), uuid.New(), uuid.New()}}, fields: []string{"product_ids"}},
+		{name: "repeated product", req: CompareRequest{ProductIDs: []uuid.UUID{a, b, a}}, fields: []string{"product_ids"}},
+		{name: "negative amount and long tenure", req: CompareRequest{ProductIDs: []uuid.UUID{a, b}, Amount: -1, TenureMonths: 481},
+			fields: []string{"amount", "tenure_months"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCompareRequest(tt.req)
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("validateCompareRequest: %v", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) || len(invalid.Fields) != len(tt.fields) {
+				t.Fatalf("validateCompareRequest error = %v, want %v", err, tt.fields)
+			}
+			for _, f := range tt.fields {
+				if invalid.Fields[f] == "" {
+					t.Errorf("no error for %s in %v", f, invalid.Fields)
+				}
+			}
+		})
+	}
+}
+
+// TestCompareProducts checks that rows keep the requested order and that the
+// cheapest EMI and the cheapest total cost are picked separately.
+func TestCompareProducts(t *testing.T) {
+	tx := testDB(t)
+	create := func(rate string, feePercent float64) uuid.UUID {
+		p := matchingtest.Product(func(p *models.LoanProduct) {
+			p.ID = uuid.Nil
+			p.InterestRate, p.ProcessingFeePercent = rate, feePercent
+			p.ProductURL = "https://example.com/compare/" + uuid.NewString()
+		})
+		if err := tx.Create(p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+		return p.ID
+	}
+	// At 300000 over 24 months the 10% product has the lower EMI, but its 5%
+	// fee makes the 11% product cheaper overall.
+	lowRate, noFee, unpriced := create("10% p.a.", 5), create("11% p.a.", 0), create("contact branch", 0)
+	ids := []uuid.UUID{unpriced, lowRate, noFee}
+
+	cmp, err := CompareProducts(CompareRequest{ProductIDs: ids, Amount: 300000, TenureMonths: 24})
+	if err != nil {
+		t.Fatalf("CompareProducts: %v", err)
+	}
+	var order []uuid.UUID
+	for _, row := range cmp.Products {
+		order = append(order, row.ProductID)
+	}
+	if !reflect.DeepEqual(order, ids) {
+		t.Errorf("rows = %v, want the requested order %v", order, ids)
+	}
+	if cmp.LowestEMI == nil || *cmp.LowestEMI != lowRate {
+		t.Errorf("lowest EMI = %v, want %s", cmp.LowestEMI, lowRate)
+	}
+	if cmp.LowestTotalCost == nil || *cmp.LowestTotalCost != noFee {
+		t.Errorf("lowest total cost = %v, want %s", cmp.LowestTotalCost, noFee)
+	}
+
+	_, err = CompareProducts(CompareRequest{ProductIDs: []uuid.UUID{lowRate, uuid.New()}})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || invalid.Fields["product_ids"] == "" {
+		t.Errorf("unknown product: err = %v, want a product_ids ValidationError", err)
+	}
+}
@@ -0,0 +1,275 @@
+package svc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/BadadheVed/clickpe/banknames"
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrProductMerged = errors.New("product has already been merged into another product")
+
+// productNameNoise are words dropped from product names before comparing,
+// besides the bank's own name: "HDFC Bank Personal Loan" and "Personal Loan"
+// on an HDFC page are the same product.
+var productNameNoise = map[string]bool{"bank": true, "the": true, "new": true, "online": true}
+
+// normalizeProductName lower-cases the name, strips punctuation and drops
+// the words of the normalized bank name and productNameNoise.
+func normalizeProductName(name, bank string) string {
+	skip := map[string]bool{}
+	for _, t := range strings.Fields(bank) {
+		skip[t] = true
+	}
+	tokens := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := tokens[:0]
+	for _, t := range tokens {
+		if !skip[t] && !productNameNoise[t] {
+			kept = append(kept, t)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// duplicateFingerprint identifies a product by lender, normalized name and
+// criteria, ignoring its URL. The bank is the linked or resolvable bank when
+// there is one, so spellings an admin has merged compare equal. The rate is
+// compared parsed, so "10.5% p.a." and "10.50%" do not differ.
+func duplicateFingerprint(p *models.LoanProduct, banks bankIndex) string {
+	bank := "name:" + banknames.Normalize(p.BankName)
+	if p.BankID != nil {
+		bank = "bank:" + p.BankID.String()
+	} else if id := banks.resolve(p.BankName); id != nil {
+		bank = "bank:" + id.String()
+	}
+	snapshot := matching.Snapshot(p)
+	snapshot.InterestRate = ""
+	payload, _ := json.Marshal(struct {
+		Bank     string                    `json:"bank"`
+		Name     string                    `json:"name"`
+		Criteria matching.CriteriaSnapshot `json:"criteria"`
+	}{bank, normalizeProductName(p.ProductName, banknames.Normalize(p.BankName)), snapshot})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+type DuplicateCandidate struct {
+	models.LoanProduct
+	ActiveMatches int `json:"active_matches"`
+}
+
+// DuplicateGroup is a set of products with the same fingerprint. The
+// suggested survivor is listed first.
+type DuplicateGroup struct {
+	Fingerprint string               `json:"fingerprint"`
+	BankName    string               `json:"bank_name"`
+	ProductName string               `json:"product_name"`
+	SurvivorID  uuid.UUID            `json:"suggested_survivor_id"`
+	Products    []DuplicateCandidate `json:"products"`
+}
+
+// FindDuplicateProducts groups products that are not yet merged by
+// fingerprint and returns the groups with more than one product. Nothing is
+// changed; an admin merges a group with MergeProducts.
+func FindDuplicateProducts() ([]DuplicateGroup, error) {
+	var products []models.LoanProduct
+	if err := database.DB.Where("status <> ?", models.ProductStatusMerged).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	banks, err := loadBankIndex(database.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	byPrint := map[string][]models.LoanProduct{}
+	for _, p := range products {
+		fp := duplicateFingerprint(&p, banks)
+		byPrint[fp] = append(byPrint[fp], p)
+	}
+
+	var counts []struct {
+		ProductID uuid.UUID
+		Matches   int
+	}
+	err = database.DB.Model(&models.Match{}).
+		Select("product_id, count(*) AS matches").
+		Where("status = ?", models.MatchStatusActive).
+		Group("product_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	matches := make(map[uuid.UUID]int, len(counts))
+	for _, c := range counts {
+		matches[c.ProductID] = c.Matches
+	}
+
+	groups := []DuplicateGroup{}
+	for fp, members := range byPrint {
+		if len(members) < 2 {
+			continue
+		}
+		group := DuplicateGroup{Fingerprint: fp}
+		for _, p := range members {
+			group.Products = append(group.Products, DuplicateCandidate{LoanProduct: p, ActiveMatches: matches[p.ID]})
+		}
+		sort.Slice(group.Products, func(i, j int) bool {
+			return preferSurvivor(&group.Products[i], &group.Products[j])
+		})
+		first := group.Products[0]
+		group.SurvivorID = first.ID
+		group.BankName = first.BankName
+		group.ProductName = first.ProductName
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].BankName != groups[j].BankName {
+			return groups[i].BankName < groups[j].BankName
+		}
+		return groups[i].ProductName < groups[j].ProductName
+	})
+	return groups, nil
+}
+
+// preferSurvivor orders the product that should survive a merge first: an
+// active product, then the one with most active matches, then the oldest.
+func preferSurvivor(a, b *DuplicateCandidate) bool {
+	if a.Active() != b.Active() {
+		return a.Active()
+	}
+	if a.ActiveMatches != b.ActiveMatches {
+		return a.ActiveMatches > b.ActiveMatches
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+type ProductMergeResult struct {
+	Survivor          models.LoanProduct `json:"survivor"`
+	Merged            []uuid.UUID        `json:"merged"`
+	MatchesMoved      int                `json:"matches_moved"`
+	MatchesSuperseded int                `json:"matches_superseded"`
+	Rematch           *RematchResult     `json:"rematch,omitempty"`
+}
+
+// MergeProducts folds the duplicates into the survivor. Each duplicate's
+// matches move to the survivor, keeping their notification and withdrawal
+// state; where the user already has a match on the survivor, the duplicate's
+// active match is superseded instead. Duplicates are set to merged and leave
+// matching, and the survivor is rematched so the moved matches are
+// re-evaluated against its criteria. Its criteria hash and last match time are
+// cleared in the merge, so the product watcher rematches it if the immediate
+// rematch fails.
+func MergeProducts(survivorID uuid.UUID, duplicateIDs []uuid.UUID, note string) (*ProductMergeResult, error) {
+	if len(duplicateIDs) == 0 {
+		return nil, &ValidationError{Fields: map[string]string{"duplicate_ids": "must list at least one product"}}
+	}
+	seen := map[uuid.UUID]bool{survivorID: true}
+	for _, id := range duplicateIDs {
+		if seen[id] {
+			return nil, &ValidationError{Fields: map[string]string{"duplicate_ids": "must not repeat a product or include the survivor"}}
+		}
+		seen[id] = true
+	}
+
+	result := &ProductMergeResult{Merged: []uuid.UUID{}}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		survivor := &result.Survivor
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(survivor, "id = ?", survivorID).Error; err != nil {
+			return err
+		}
+		if survivor.Status == models.ProductStatusMerged {
+			return ErrProductMerged
+		}
+
+		var duplicates []models.LoanProduct
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", duplicateIDs).Find(&duplicates).Error
+		if err != nil {
+			return err
+		}
+		if len(duplicates) != len(duplicateIDs) {
+			return &ValidationError{Fields: map[string]string{"duplicate_ids": "unknown products"}}
+		}
+
+		reason := "merged into " + survivor.ID.String()
+		if note != "" {
+			reason += ": " + note
+		}
+		for i := range duplicates {
+			dup := &duplicates[i]
+			if dup.Status == models.ProductStatusMerged {
+				return fmt.Errorf("product %s: %w", dup.ID, ErrProductMerged)
+			}
+			moved, superseded, err := moveProductMatches(tx, dup.ID, survivor.ID, "product "+reason)
+			if err != nil {
+				return err
+			}
+			result.MatchesMoved += moved
+			result.MatchesSuperseded += superseded
+
+			err = setProductStatus(tx, dup, models.ProductStatusMerged, reason,
+				map[string]interface{}{"merged_into_id": survivor.ID})
+			if err != nil {
+				return err
+			}
+			result.Merged = append(result.Merged, dup.ID)
+		}
+
+		survivor.CriteriaHash = ""
+		survivor.LastMatchedAt = nil
+		return tx.Model(survivor).UpdateColumns(map[string]interface{}{
+			"criteria_hash":   "",
+			"last_matched_at": nil,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("MergeProducts: Merged", "survivor_id", survivorID, "merged", result.Merged,
+		"matches_moved", result.MatchesMoved, "matches_superseded", result.MatchesSuperseded)
+
+	rematch, err := RematchProduct(&result.Survivor, TriggerProductMerge)
+	if err != nil {
+		slog.Error("MergeProducts: Rematch failed, left to the product watcher", "product_id", survivorID, "error", err)
+		return result, nil
+	}
+	result.Rematch = &rematch
+	return result, nil
+}
+
+// moveProductMatches moves the matches of from to to, except for users who
+// already have a match on to, whose active matches on from are superseded.
+func moveProductMatches(tx *gorm.DB, from, to uuid.UUID, reason string) (moved, superseded int, err error) {
+	existing := tx.Model(&models.Match{}).Select("user_id").Where("product_id = ?", to)
+
+	superseded, err = transitionMatches(tx.Where("product_id = ? AND user_id IN (?)", from, existing),
+		models.MatchStatusSuperseded, reason)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	res := tx.Model(&models.Match{}).
+		Where("product_id = ? AND user_id NOT IN (?)", from, existing).
+		UpdateColumn("product_id", to)
+	if res.Error != nil {
+		return 0, 0, res.Error
+	}
+	return int(res.RowsAffected), superseded, nil
+}
@@ -0,0 +1,218 @@
+package svc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestDuplicateFingerprint(t *testing.T) {
+	hdfc := uuid.New()
+	banks := bankIndex{"hdfc": hdfc, "housing development finance": hdfc}
+	product := func(bank, name string, opts ...func(*models.LoanProduct)) *models.LoanProduct {
+		return matchingtest.Product(append([]func(*models.LoanProduct){func(p *models.LoanProduct) {
+			p.BankName, p.ProductName = bank, name
+		}}, opts...)...)
+	}
+	base := product("HDFC Bank", "Personal Loan")
+
+	tests := []struct {
+		name  string
+		other *models.LoanProduct
+		same  bool
+	}{
+		{"bank name in the product name", product("HDFC Bank", "HDFC Bank Personal Loan"), true},
+		{"noise words and punctuation", product("HDFC Bank", "The New Personal-Loan (Online)"), true},
+		{"bank spelling", product("HDFC Bank Ltd.", "Personal Loan"), true},
+		{"bank alias", product("Housing Development Finance", "Personal Loan"), true},
+		{"linked bank", product("HDFC Bank", "Personal Loan", func(p *models.LoanProduct) { p.BankID = &hdfc }), true},
+		{"rate written differently", product("HDFC Bank", "Personal Loan", func(p *models.LoanProduct) {
+			p.InterestRate = "10.50% - 24.00%"
+			p.ParseInterestRate()
+		}), true},
+		{"different URL", product("HDFC Bank", "Personal Loan", func(p *models.LoanProduct) { p.ProductURL = "https://example.com/other" }), true},
+		{"different product", product("HDFC Bank", "Home Loan"), false},
+		{"different bank", product("ICICI Bank", "Personal Loan"), false},
+		{"different criteria", product("HDFC Bank", "Personal Loan", func(p *models.LoanProduct) { p.MinCreditScore = 700 }), false},
+		{"different rate", product("HDFC Bank", "Personal Loan", func(p *models.LoanProduct) {
+			p.InterestRate = "11% - 24% p.a."
+			p.ParseInterestRate()
+		}), false},
+	}
+	want := duplicateFingerprint(base, banks)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := duplicateFingerprint(tt.other, banks); (got == want) != tt.same {
+				t.Errorf("fingerprints equal = %t, want %t", got == want, tt.same)
+			}
+		})
+	}
+}
+
+func TestPreferSurvivor(t *testing.T) {
+	older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+	candidate := func(status models.ProductStatus, matches int, created time.Time, id string) DuplicateCandidate {
+		return DuplicateCandidate{
+			LoanProduct:   models.LoanProduct{ID: uuid.MustParse(id), Status: status, CreatedAt: created},
+			ActiveMatches: matches,
+		}
+	}
+	const lowID, highID = "00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"
+
+	tests := []struct {
+		name        string
+		first, then DuplicateCandidate
+	}{
+		{"active before inactive", candidate(models.ProductStatusActive, 0, newer, highID), candidate(models.ProductStatusInactive, 50, older, lowID)},
+		{"active before pending review", candidate(models.ProductStatusActive, 0, newer, highID), candidate(models.ProductStatusPendingReview, 50, older, lowID)},
+		{"more active matches", candidate(models.ProductStatusActive, 10, newer, highID), candidate(models.ProductStatusActive, 2, older, lowID)},
+		{"older", candidate(models.ProductStatusActive, 2, older, highID), candidate(models.ProductStatusActive, 2, newer, lowID)},
+		{"lower id", candidate(models.ProductStatusActive, 2, older, lowID), candidate(models.ProductStatusActive, 2, older, highID)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !preferSurvivor(&tt.first, &tt.then) {
+				t.Error("preferSurvivor(first, then) = false, want true")
+			}
+			if preferSurvivor(&tt.then, &tt.first) {
+				t.Error("preferSurvivor(then, first) = true, want false")
+			}
+		})
+	}
+}
+
+func TestMergeProductsRejectsDuplicateIDs(t *testing.T) {
+	survivor, dup := uuid.New(), uuid.New()
+	tests := []struct {
+		name string
+		ids  []uuid.UUID
+	}{
+		{"no duplicates", nil},
+		{"repeated duplicate", []uuid.UUID{dup, dup}},
+		{"survivor listed", []uuid.UUID{dup, survivor}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MergeProducts(survivor, tt.ids, "")
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) || invalid.Fields["duplicate_ids"] == "" {
+				t.Fatalf("MergeProducts error = %v, want a duplicate_ids ValidationError", err)
+			}
+		})
+	}
+}
+
+// seedMergeProducts creates n active products with distinct URLs.
+func seedMergeProducts(t *testing.T, tx *gorm.DB, n int) []models.LoanProduct {
+	t.Helper()
+	products := make([]models.LoanProduct, n)
+	for i := range products {
+		products[i] = *matchingtest.Product(func(p *models.LoanProduct) {
+			p.ID = uuid.Nil
+			p.ProductURL = "https://example.com/merge/" + uuid.NewString()
+		})
+		if err := tx.Create(&products[i]).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+	return products
+}
+
+func TestMoveProductMatches(t *testing.T) {
+	tx := testDB(t)
+	products := seedMergeProducts(t, tx, 2)
+	from, to := products[0].ID, products[1].ID
+
+	users := make([]models.User, 4)
+	for i := range users {
+		users[i] = models.User{Name: "Merge User", Email: uuid.NewString() + "@example.com", MonthlyIncome: 50000, CreditScore: 750}
+	}
+	if err := tx.Create(&users).Error; err != nil {
+		t.Fatalf("create users: %v", err)
+	}
+	moves, keepsSurvivor, supersedes, withdrawnOnBoth := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+
+	matches := []models.Match{
+		{UserID: moves, ProductID: from, Status: models.MatchStatusActive, IsNotified: true},
+		{UserID: keepsSurvivor, ProductID: from, Status: models.MatchStatusWithdrawn},
+		{UserID: supersedes, ProductID: from, Status: models.MatchStatusActive},
+		{UserID: supersedes, ProductID: to, Status: models.MatchStatusActive},
+		{UserID: withdrawnOnBoth, ProductID: from, Status: models.MatchStatusWithdrawn},
+		{UserID: withdrawnOnBoth, ProductID: to, Status: models.MatchStatusExpired},
+	}
+	if err := tx.Create(&matches).Error; err != nil {
+		t.Fatalf("create matches: %v", err)
+	}
+
+	moved, superseded, err := moveProductMatches(tx, from, to, "product merged")
+	if err != nil {
+		t.Fatalf("moveProductMatches: %v", err)
+	}
+	if moved != 2 || superseded != 1 {
+		t.Errorf("moved, superseded = %d, %d, want 2, 1", moved, superseded)
+	}
+
+	tests := []struct {
+		name       string
+		match      models.Match
+		product    uuid.UUID
+		status     models.MatchStatus
+		isNotified bool
+	}{
+		{"active match moves with its notification state", matches[0], to, models.MatchStatusActive, true},
+		{"withdrawn match moves and stays withdrawn", matches[1], to, models.MatchStatusWithdrawn, false},
+		{"match held on both is superseded", matches[2], from, models.MatchStatusSuperseded, false},
+		{"survivor match is kept", matches[3], to, models.MatchStatusActive, false},
+		{"inactive match held on both stays", matches[4], from, models.MatchStatusWithdrawn, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.Match
+			if err := tx.First(&got, "id = ?", tt.match.ID).Error; err != nil {
+				t.Fatalf("load match: %v", err)
+			}
+			if got.ProductID != tt.product || got.Status != tt.status || got.IsNotified != tt.isNotified {
+				t.Errorf("match on %s is %s (notified %t), want on %s, %s (notified %t)",
+					got.ProductID, got.Status, got.IsNotified, tt.product, tt.status, tt.isNotified)
+			}
+		})
+	}
+}
+
+func TestMergeProductsRejectsMergedProducts(t *testing.T) {
+	tx := testDB(t)
+	products := seedMergeProducts(t, tx, 3)
+	merged := &products[2]
+	if err := tx.Model(merged).UpdateColumn("status", models.ProductStatusMerged).Error; err != nil {
+		t.Fatalf("mark merged: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		survivor   uuid.UUID
+		duplicates []uuid.UUID
+	}{
+		{"merged survivor", merged.ID, []uuid.UUID{products[0].ID}},
+		{"merged duplicate", products[0].ID, []uuid.UUID{products[1].ID, merged.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MergeProducts(tt.survivor, tt.duplicates, ""); !errors.Is(err, ErrProductMerged) {
+				t.Fatalf("MergeProducts error = %v, want ErrProductMerged", err)
+			}
+			var dup models.LoanProduct
+			if err := tx.First(&dup, "id = ?", products[1].ID).Error; err != nil {
+				t.Fatalf("load product: %v", err)
+			}
+			if dup.Status != models.ProductStatusActive {
+				t.Errorf("product status after a rejected merge = %s, want active", dup.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package svc
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// historyFields are the product criteria tracked in ProductHistory, in diff
+// order: every field matching evaluates.
+var historyFields = []string{
+	"min_credit_score", "min_monthly_income", "age", "interest_rate", "raw_criteria",
+	"max_foir", "min_loan_amount", "max_loan_amount", "min_tenure_months", "max_tenure_months",
+	"loan_type", "serviceable_pincodes", "serviceable_cities", "city_tiers",
+}
+
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// historyCriteria renders the tracked fields as canonical JSON values, so
+// equal criteria always compare equal regardless of key order in raw_criteria.
+func historyCriteria(p *models.LoanProduct) map[string]json.RawMessage {
+	raw := json.RawMessage("null")
+	if len(p.RawCriteria) > 0 {
+		var v interface{}
+		if err := json.Unmarshal(p.RawCriteria, &v); err == nil {
+			raw, _ = json.Marshal(v)
+		} else {
+			raw, _ = json.Marshal(string(p.RawCriteria))
+		}
+	}
+	values := map[string]json.RawMessage{"raw_criteria": raw}
+	values["min_credit_score"], _ = json.Marshal(p.MinCreditScore)
+	values["min_monthly_income"], _ = json.Marshal(p.MinMonthlyIncome)
+	values["age"], _ = json.Marshal(p.Age)
+	values["interest_rate"], _ = json.Marshal(p.InterestRate)
+	values["max_foir"], _ = json.Marshal(p.MaxFOIR)
+	values["min_loan_amount"], _ = json.Marshal(p.MinLoanAmount)
+	values["max_loan_amount"], _ = json.Marshal(p.MaxLoanAmount)
+	values["min_tenure_months"], _ = json.Marshal(p.MinTenureMonths)
+	values["max_tenure_months"], _ = json.Marshal(p.MaxTenureMonths)
+	values["loan_type"], _ = json.Marshal(p.LoanType)
+	values["serviceable_pincodes"], _ = json.Marshal(sortedCopy(p.ServiceablePincodes))
+	values["serviceable_cities"], _ = json.Marshal(sortedCopy(p.ServiceableCities))
+	values["city_tiers"], _ = json.Marshal(sortedCopy(p.CityTiers))
+	return values
+}
+
+// sortedCopy orders a serviceability list, whose order carries no meaning, so
+// a crawler reordering it does not record a new version.
+func sortedCopy[T cmp.Ordered](values []T) []T {
+	out := slices.Clone(values)
+	slices.Sort(out)
+	if out == nil {
+		out = []T{}
+	}
+	return out
+}
+
+func diffCriteria(before, after map[string]json.RawMessage) []FieldChange {
+	var changes []FieldChange
+	for _, field := range historyFields {
+		from, to := before[field], after[field]
+		from = canonicalJSON(from)
+		if !bytes.Equal(from, to) {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+// recordProductHistory appends a version when the product's criteria differ
+// from its latest recorded version, and returns it. It must run in the
+// transaction that writes the product.
+func recordProductHistory(tx *gorm.DB, product *models.LoanProduct, source models.ProductChangeSource) (*models.ProductHistory, error) {
+	var latest models.ProductHistory
+	err := tx.Where("product_id = ?", product.ID).Order("version DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return nil, err
+	}
+
+	before := map[string]json.RawMessage{}
+	if latest.Version > 0 {
+		if err := json.Unmarshal(latest.Criteria, &before); err != nil {
+			return nil, err
+		}
+	}
+	after := historyCriteria(product)
+	changes := diffCriteria(before, after)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	diff, _ := json.Marshal(changes)
+	criteria, _ := json.Marshal(after)
+	entry := models.ProductHistory{
+		ProductID: product.ID,
+		Version:   latest.Version + 1,
+		Source:    source,
+		Diff:      diff,
+		Criteria:  criteria,
+		ChangedAt: time.Now(),
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	slog.Info("Product criteria change recorded", "product_id", product.ID, "version", entry.Version, "source", source, "fields", len(changes))
+	return &entry, nil
+}
+
+// recordProductChange versions the product's criteria and, when a version
+// was added, decides whether it needs review.
+func recordProductChange(tx *gorm.DB, product *models.LoanProduct, source models.ProductChangeSource) (bool, error) {
+	entry, err := recordProductHistory(tx, product, source)
+	if err != nil || entry == nil {
+		return false, err
+	}
+	return true, reviewProductChange(tx, product, entry)
+}
+
+// RecordExternalProductChanges versions criteria edits written straight to
+// Postgres by the crawler. Products without history get their first version.
+// New and materially changed products are put in review.
+func RecordExternalProductChanges(products []models.LoanProduct) (int, error) {
+	recorded := 0
+	for i := range products {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			ok, err := recordProductChange(tx, &products[i], models.ProductSourceCrawler)
+			if ok {
+				recorded++
+			}
+			return err
+		})
+		if err != nil {
+			slog.Error("RecordExternalProductChanges: Failed", "product_id", products[i].ID, "error", err)
+			return recorded, err
+		}
+	}
+	return recorded, nil
+}
+
+// ListProductHistory returns a product's criteria versions, newest first.
+func ListProductHistory(productID uuid.UUID) ([]models.ProductHistory, error) {
+	if _, err := GetProduct(productID); err != nil {
+		return nil, err
+	}
+	var history []models.ProductHistory
+	err := database.DB.Where("product_id = ?", productID).Order("version DESC").Find(&history).Error
+	return history, err
+}
+
+// ValidChangeSource reports whether s may be given by an API caller. The
+// crawler source is reserved for bulk ingestion and direct writes.
+func ValidChangeSource(s models.ProductChangeSource) bool {
+	return s == models.ProductSourceAPI || s == models.ProductSourceAdmin
+}
+
+// canonicalJSON re-encodes a stored value, since jsonb changes whitespace and
+// key order.
+func canonicalJSON(raw json.RawMessage) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return json.RawMessage("null")
+	}
+	out, _ := json.Marshal(v)
+	return out
+}
@@ -0,0 +1,161 @@
+package svc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+func TestHistoryCriteria(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*models.LoanProduct)
+		field  string
+		want   string
+	}{
+		{"no raw criteria", func(p *models.LoanProduct) { p.RawCriteria = nil }, "raw_criteria", `null`},
+		{"raw criteria key order", func(p *models.LoanProduct) {
+			p.RawCriteria = datatypes.JSON(`{"max_age": 60, "income_multiplier": 2}`)
+		}, "raw_criteria", `{"income_multiplier":2,"max_age":60}`},
+		{"raw criteria not JSON", func(p *models.LoanProduct) { p.RawCriteria = datatypes.JSON(`salaried only`) },
+			"raw_criteria", `"salaried only"`},
+		{"pincodes sorted", func(p *models.LoanProduct) { p.ServiceablePincodes = []string{"560", "411001"} },
+			"serviceable_pincodes", `["411001","560"]`},
+		{"no cities", func(p *models.LoanProduct) { p.ServiceableCities = nil }, "serviceable_cities", `[]`},
+		{"tiers sorted", func(p *models.LoanProduct) { p.CityTiers = []int{3, 1} }, "city_tiers", `[1,3]`},
+		{"income", func(p *models.LoanProduct) { p.MinMonthlyIncome = 25000.5 }, "min_monthly_income", `25000.5`},
+		{"loan type", func(p *models.LoanProduct) { p.LoanType = models.LoanTypeHome }, "loan_type", `"home"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := historyCriteria(matchingtest.Product(tt.change))
+			if len(values) != len(historyFields) {
+				t.Errorf("historyCriteria has %d fields, want %d", len(values), len(historyFields))
+			}
+			if got := string(values[tt.field]); got != tt.want {
+				t.Errorf("%s = %s, want %s", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffCriteria(t *testing.T) {
+	base := matchingtest.Product(matchingtest.WithRawCriteria(map[string]any{"max_age": 60, "income_multiplier": 2}))
+	after := func(change func(*models.LoanProduct)) map[string]json.RawMessage {
+		p := *base
+		change(&p)
+		return historyCriteria(&p)
+	}
+	// stored mimics a version read back from jsonb, with its spacing.
+	stored := map[string]json.RawMessage{}
+	for field, v := range historyCriteria(base) {
+		var decoded any
+		if err := json.Unmarshal(v, &decoded); err != nil {
+			t.Fatalf("decode %s: %v", field, err)
+		}
+		stored[field], _ = json.MarshalIndent(decoded, "", "  ")
+	}
+	untracked := map[string]json.RawMessage{}
+	for field, v := range stored {
+		if field != "max_foir" {
+			untracked[field] = v
+		}
+	}
+
+	tests := []struct {
+		name   string
+		before map[string]json.RawMessage
+		after  map[string]json.RawMessage
+		want   []FieldChange
+	}{
+		{name: "unchanged", before: stored, after: after(func(*models.LoanProduct) {})},
+		{name: "empty list", before: stored, after: after(func(p *models.LoanProduct) { p.CityTiers = []int{} })},
+		{name: "threshold", before: stored, after: after(func(p *models.LoanProduct) { p.MinCreditScore = 700 }),
+			want: []FieldChange{{Field: "min_credit_score", From: json.RawMessage(`650`), To: json.RawMessage(`700`)}}},
+		{name: "fields in tracked order", before: stored, after: after(func(p *models.LoanProduct) {
+			p.LoanType, p.Age, p.RawCriteria = models.LoanTypeHome, 25, nil
+		}), want: []FieldChange{
+			{Field: "age", From: json.RawMessage(`21`), To: json.RawMessage(`25`)},
+			{Field: "raw_criteria", From: json.RawMessage(`{"income_multiplier":2,"max_age":60}`), To: json.RawMessage(`null`)},
+			{Field: "loan_type", From: json.RawMessage(`""`), To: json.RawMessage(`"home"`)},
+		}},
+		{name: "field not tracked before", before: untracked, after: after(func(*models.LoanProduct) {}),
+			want: []FieldChange{{Field: "max_foir", From: json.RawMessage(`null`), To: json.RawMessage(`0`)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffCriteria(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffCriteria = %s, want %s", marshal(got), marshal(tt.want))
+			}
+		})
+	}
+}
+
+func marshal(v any) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// TestRecordProductHistory checks that only criteria edits add a version.
+func TestRecordProductHistory(t *testing.T) {
+	tx := testDB(t)
+	in := ProductInput{
+		BankName: "History Bank", ProductName: "History Personal Loan", InterestRate: "10.5% - 24% p.a.",
+		MinCreditScore: 650, MinMonthlyIncome: 15000, Age: 21, ServiceableCities: []string{"Pune", "Mumbai"},
+		RawCriteria: json.RawMessage(`{"max_age": 60, "income_multiplier": 2}`),
+		ProductURL:  "https://example.com/history/" + uuid.NewString(),
+	}
+	product, err := CreateProduct(in, models.ProductSourceAPI)
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		change      func(*ProductInput)
+		wantVersion int
+		wantFields  []string
+	}{
+		{"name only", func(in *ProductInput) { in.ProductName = "History Personal Loan Plus" }, 1, nil},
+		{"raw criteria reordered", func(in *ProductInput) {
+			in.RawCriteria = json.RawMessage(`{"income_multiplier":2,"max_age":60}`)
+		}, 1, nil},
+		{"cities reordered", func(in *ProductInput) { in.ServiceableCities = []string{"Mumbai", "Pune"} }, 1, nil},
+		{"criteria", func(in *ProductInput) { in.MinCreditScore, in.MaxFOIR = 700, 0.5 }, 2, []string{"min_credit_score", "max_foir"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change(&in)
+			if _, err := UpdateProduct(product.ID, in, models.ProductSourceAPI); err != nil {
+				t.Fatalf("UpdateProduct: %v", err)
+			}
+			var latest models.ProductHistory
+			if err := tx.Where("product_id = ?", product.ID).Order("version DESC").First(&latest).Error; err != nil {
+				t.Fatalf("load history: %v", err)
+			}
+			if latest.Version != tt.wantVersion {
+				t.Fatalf("latest version = %d, want %d", latest.Version, tt.wantVersion)
+			}
+			if tt.wantFields == nil {
+				return
+			}
+			var changes []FieldChange
+			if err := json.Unmarshal(latest.Diff, &changes); err != nil {
+				t.Fatalf("decode diff: %v", err)
+			}
+			var fields []string
+			for _, c := range changes {
+				fields = append(fields, c.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("changed fields = %q, want %q", fields, tt.wantFields)
+			}
+		})
+	}
+}
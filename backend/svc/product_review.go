@@ -0,0 +1,282 @@
+package svc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/BadadheVed/clickpe/rates"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductReviewTolerance is the largest relative change in a numeric
+// criterion a crawled update may make against the last approved version
+// without needing review.
+var ProductReviewTolerance = 0.25
+
+// reviewThresholds are the numeric criteria checked for material changes.
+var reviewThresholds = []string{
+	"min_credit_score", "min_monthly_income", "age", "max_foir",
+	"min_loan_amount", "max_loan_amount", "min_tenure_months", "max_tenure_months",
+}
+
+// reviewExactFields are the criteria any change to which needs review.
+var reviewExactFields = []string{"loan_type", "serviceable_pincodes", "serviceable_cities", "city_tiers", "raw_criteria"}
+
+var ErrProductNotInReview = errors.New("product is not awaiting review")
+
+// reviewProductChange decides what a newly recorded version means for the
+// product's status. Changes made through the API are trusted: the version is
+// approved and a product held for review is released. Crawled versions are
+// approved when they stay close to the last approved version; new products
+// and material changes are held in pending_review. A held product gets no new
+// matches, and keeps the ones it has until the review is resolved.
+func reviewProductChange(tx *gorm.DB, product *models.LoanProduct, entry *models.ProductHistory) error {
+	if product.Status == models.ProductStatusMerged {
+		// Merged duplicates keep their history but never return to matching.
+		return nil
+	}
+	reasons, err := reviewReasons(tx, product, entry)
+	if err != nil {
+		return err
+	}
+
+	if len(reasons) > 0 {
+		reason := strings.Join(reasons, "; ")
+		slog.Info("Product held for review", "product_id", product.ID, "version", entry.Version, "reason", reason)
+		return setProductStatus(tx, product, models.ProductStatusPendingReview, reason, nil)
+	}
+
+	columns := map[string]interface{}{"approved_version": entry.Version}
+	product.ApprovedVersion = entry.Version
+	if product.Status == models.ProductStatusPendingReview || product.Status == models.ProductStatusRejected {
+		reason := fmt.Sprintf("version %d approved from %s", entry.Version, entry.Source)
+		return setProductStatus(tx, product, models.ProductStatusActive, reason, columns)
+	}
+	return tx.Model(product).UpdateColumns(columns).Error
+}
+
+// reviewReasons lists why a version needs an admin's approval, if it does.
+func reviewReasons(tx *gorm.DB, product *models.LoanProduct, entry *models.ProductHistory) ([]string, error) {
+	if entry.Source != models.ProductSourceCrawler {
+		return nil, nil
+	}
+	if product.ApprovedVersion == 0 {
+		// Products matched before reviews existed have their first version
+		// recorded late; it is their baseline, not a new product.
+		if entry.Version == 1 && product.LastMatchedAt != nil && product.Active() {
+			return nil, nil
+		}
+		return []string{"new product awaiting approval"}, nil
+	}
+
+	approved, err := approvedCriteria(tx, product)
+	if err != nil {
+		return nil, err
+	}
+	return materialChanges(approved, historyCriteria(product)), nil
+}
+
+func approvedCriteria(tx *gorm.DB, product *models.LoanProduct) (map[string]json.RawMessage, error) {
+	criteria := map[string]json.RawMessage{}
+	if product.ApprovedVersion == 0 {
+		return criteria, nil
+	}
+	var approved models.ProductHistory
+	err := tx.Where("product_id = ? AND version = ?", product.ID, product.ApprovedVersion).First(&approved).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(approved.Criteria, &criteria); err != nil {
+		return nil, err
+	}
+	return criteria, nil
+}
+
+// materialChanges flags numeric thresholds that were dropped to zero or moved
+// by more than ProductReviewTolerance, an effective APR that moved by more
+// than the tolerance or stopped parsing, and any change to the other criteria
+// matching evaluates. Adding a threshold where there was none only narrows
+// matching and is not flagged. Fields missing from the approved version were
+// not tracked when it was recorded and are skipped.
+func materialChanges(before, after map[string]json.RawMessage) []string {
+	var reasons []string
+	for _, field := range reviewThresholds {
+		var from, to float64
+		if json.Unmarshal(before[field], &from) != nil || json.Unmarshal(after[field], &to) != nil || from <= 0 {
+			continue
+		}
+		switch {
+		case to == 0:
+			reasons = append(reasons, fmt.Sprintf("%s dropped from %v to 0", field, from))
+		case math.Abs(to-from)/from > ProductReviewTolerance:
+			reasons = append(reasons, fmt.Sprintf("%s changed from %v to %v", field, from, to))
+		}
+	}
+	if reason := rateChange(before["interest_rate"], after["interest_rate"]); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	for _, field := range reviewExactFields {
+		if _, tracked := before[field]; !tracked {
+			continue
+		}
+		if !bytes.Equal(canonicalJSON(before[field]), canonicalJSON(after[field])) {
+			reasons = append(reasons, field+" changed")
+		}
+	}
+	return reasons
+}
+
+// rateChange compares the effective APR of two interest rate strings, since
+// matching prices EMI and FOIR with it.
+func rateChange(before, after json.RawMessage) string {
+	var from, to string
+	if json.Unmarshal(before, &from) != nil || json.Unmarshal(after, &to) != nil || from == to {
+		return ""
+	}
+	fromRange, _ := rates.Parse(from)
+	toRange, _ := rates.Parse(to)
+	fromAPR, ok := fromRange.Effective()
+	if !ok || fromAPR <= 0 {
+		return ""
+	}
+	toAPR, ok := toRange.Effective()
+	switch {
+	case !ok:
+		return fmt.Sprintf("interest_rate %q no longer parses", to)
+	case math.Abs(toAPR-fromAPR)/fromAPR > ProductReviewTolerance:
+		return fmt.Sprintf("interest_rate changed from %q to %q", from, to)
+	}
+	return ""
+}
+
+// ProductReview shows what an admin is asked to approve: the product's
+// current criteria against its last approved version.
+type ProductReview struct {
+	Product         models.LoanProduct `json:"product"`
+	ApprovedVersion int                `json:"approved_version"`
+	CurrentVersion  int                `json:"current_version"`
+	Changes         []FieldChange      `json:"changes"`
+}
+
+func buildProductReview(tx *gorm.DB, product *models.LoanProduct) (*ProductReview, error) {
+	approved, err := approvedCriteria(tx, product)
+	if err != nil {
+		return nil, err
+	}
+	var current int
+	err = tx.Model(&models.ProductHistory{}).
+		Where("product_id = ?", product.ID).
+		Select("COALESCE(max(version), 0)").
+		Scan(&current).Error
+	if err != nil {
+		return nil, err
+	}
+	changes := diffCriteria(approved, historyCriteria(product))
+	if changes == nil {
+		changes = []FieldChange{}
+	}
+	return &ProductReview{
+		Product:         *product,
+		ApprovedVersion: product.ApprovedVersion,
+		CurrentVersion:  current,
+		Changes:         changes,
+	}, nil
+}
+
+// ListProductReviews returns the products awaiting review, oldest first.
+func ListProductReviews() ([]ProductReview, error) {
+	var products []models.LoanProduct
+	err := database.DB.
+		Where("status = ?", models.ProductStatusPendingReview).
+		Order("status_changed_at").
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	reviews := make([]ProductReview, 0, len(products))
+	for i := range products {
+		review, err := buildProductReview(database.DB, &products[i])
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, *review)
+	}
+	return reviews, nil
+}
+
+func GetProductReview(id uuid.UUID) (*ProductReview, error) {
+	product, err := GetProduct(id)
+	if err != nil {
+		return nil, err
+	}
+	return buildProductReview(database.DB, product)
+}
+
+// ApproveProduct approves the product's latest criteria version and returns
+// it to matching. Rejected products can be approved later as well.
+func ApproveProduct(id uuid.UUID, note string) (*models.LoanProduct, error) {
+	return resolveReview(id, func(tx *gorm.DB, product *models.LoanProduct) error {
+		if product.Status != models.ProductStatusPendingReview && product.Status != models.ProductStatusRejected {
+			return ErrProductNotInReview
+		}
+		var latest int
+		err := tx.Model(&models.ProductHistory{}).
+			Where("product_id = ?", product.ID).
+			Select("COALESCE(max(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("version %d approved", latest)
+		if note != "" {
+			reason += ": " + note
+		}
+		product.ApprovedVersion = latest
+		return setProductStatus(tx, product, models.ProductStatusActive, reason,
+			map[string]interface{}{"approved_version": latest})
+	})
+}
+
+// RejectProduct keeps a product held for review out of matching until the
+// crawler reports different criteria or an admin approves it. The matches it
+// kept while under review are expired.
+func RejectProduct(id uuid.UUID, note string) (*models.LoanProduct, error) {
+	return resolveReview(id, func(tx *gorm.DB, product *models.LoanProduct) error {
+		if product.Status != models.ProductStatusPendingReview {
+			return ErrProductNotInReview
+		}
+		reason := "rejected"
+		if note != "" {
+			reason += ": " + note
+		}
+		if err := setProductStatus(tx, product, models.ProductStatusRejected, reason, nil); err != nil {
+			return err
+		}
+		_, err := transitionMatches(tx.Where("product_id = ?", product.ID), models.MatchStatusExpired, "product "+reason)
+		return err
+	})
+}
+
+func resolveReview(id uuid.UUID, resolve func(tx *gorm.DB, product *models.LoanProduct) error) (*models.LoanProduct, error) {
+	var product models.LoanProduct
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return resolve(tx, &product)
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Product review resolved", "product_id", id, "status", product.Status, "reason", product.StatusReason)
+	return &product, nil
+}
@@ -0,0 +1,138 @@
+package svc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/models"
+)
+
+func criteria(t *testing.T, fields map[string]any) map[string]json.RawMessage {
+	t.Helper()
+	out := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", k, err)
+		}
+		out[k] = raw
+	}
+	return out
+}
+
+func TestMaterialChanges(t *testing.T) {
+	approved := map[string]any{
+		"min_credit_score":   700,
+		"min_monthly_income": 25000,
+		"interest_rate":      "10.5% - 24% p.a.",
+		"loan_type":          "personal",
+		"raw_criteria":       map[string]any{"rules": []string{"employment_status_salaried"}, "income_multiplier": 2},
+	}
+	with := func(changes map[string]any) map[string]any {
+		out := map[string]any{}
+		for k, v := range approved {
+			out[k] = v
+		}
+		for k, v := range changes {
+			out[k] = v
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		before map[string]any
+		after  map[string]any
+		want   []string
+	}{
+		{name: "unchanged", before: approved, after: approved},
+		{name: "within tolerance", before: approved, after: with(map[string]any{"min_credit_score": 650, "min_monthly_income": 30000})},
+		{name: "threshold moved too far", before: approved, after: with(map[string]any{"min_monthly_income": 10000}),
+			want: []string{"min_monthly_income changed from 25000 to 10000"}},
+		{name: "threshold dropped", before: approved, after: with(map[string]any{"min_credit_score": 0}),
+			want: []string{"min_credit_score dropped from 700 to 0"}},
+		{name: "threshold added", before: with(map[string]any{"age": 0}), after: with(map[string]any{"age": 21})},
+		{name: "rate within tolerance", before: approved, after: with(map[string]any{"interest_rate": "11% - 24% p.a."})},
+		{name: "rate moved too far", before: approved, after: with(map[string]any{"interest_rate": "18% - 30% p.a."}),
+			want: []string{`interest_rate changed from "10.5% - 24% p.a." to "18% - 30% p.a."`}},
+		{name: "rate no longer parses", before: approved, after: with(map[string]any{"interest_rate": "contact branch"}),
+			want: []string{`interest_rate "contact branch" no longer parses`}},
+		{name: "raw criteria key order", before: approved,
+			after: with(map[string]any{"raw_criteria": json.RawMessage(`{"income_multiplier": 2, "rules": ["employment_status_salaried"]}`)})},
+		{name: "raw criteria changed", before: approved, after: with(map[string]any{"raw_criteria": map[string]any{"rules": []string{}}}),
+			want: []string{"raw_criteria changed"}},
+		{name: "untracked field", before: approved, after: with(map[string]any{"serviceable_cities": []string{"Pune"}})},
+		{name: "several changes", before: approved, after: with(map[string]any{"min_credit_score": 0, "loan_type": "home"}),
+			want: []string{"min_credit_score dropped from 700 to 0", "loan_type changed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := materialChanges(criteria(t, tt.before), criteria(t, tt.after))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("materialChanges = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReviewHoldKeepsMatches checks that a crawled change held for review
+// leaves the product's matches alone until the review is resolved.
+func TestReviewHoldKeepsMatches(t *testing.T) {
+	tx := testDB(t)
+	in := ProductInput{
+		BankName: "Review Bank", ProductName: "Review Personal Loan", InterestRate: "10.5% - 24% p.a.",
+		MinCreditScore: 650, MinMonthlyIncome: 15000, Age: 21, ProductURL: "https://example.com/review/personal",
+	}
+	product, err := CreateProduct(in, models.ProductSourceAPI)
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	user := models.User{Name: "Review User", Email: "review@example.com", Age: 30, MonthlyIncome: 20000, CreditScore: 700}
+	if err := tx.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := RematchProduct(product, TriggerManual); err != nil {
+		t.Fatalf("RematchProduct: %v", err)
+	}
+	matchStatus := func() models.MatchStatus {
+		t.Helper()
+		var m models.Match
+		if err := tx.First(&m, "user_id = ? AND product_id = ?", user.ID, product.ID).Error; err != nil {
+			t.Fatalf("load match: %v", err)
+		}
+		return m.Status
+	}
+	if got := matchStatus(); got != models.MatchStatusActive {
+		t.Fatalf("match status after matching = %s, want active", got)
+	}
+
+	// The user no longer meets the proposed minimum income.
+	in.MinMonthlyIncome = 40000
+	product, _, err = UpsertProductByURL(in, models.ProductSourceCrawler)
+	if err != nil {
+		t.Fatalf("UpsertProductByURL: %v", err)
+	}
+	if product.Status != models.ProductStatusPendingReview {
+		t.Fatalf("product status = %s, want pending_review", product.Status)
+	}
+	if _, err := RematchProduct(product, TriggerManual); err != nil {
+		t.Fatalf("RematchProduct: %v", err)
+	}
+	if _, err := SweepMatches(); err != nil {
+		t.Fatalf("SweepMatches: %v", err)
+	}
+	if _, err := RunMatching(MatchModeGo); err != nil {
+		t.Fatalf("RunMatching: %v", err)
+	}
+	if got := matchStatus(); got != models.MatchStatusActive {
+		t.Fatalf("match status under review = %s, want active", got)
+	}
+
+	if _, err := RejectProduct(product.ID, "income floor looks wrong"); err != nil {
+		t.Fatalf("RejectProduct: %v", err)
+	}
+	if got := matchStatus(); got != models.MatchStatusExpired {
+		t.Fatalf("match status after rejection = %s, want expired", got)
+	}
+}
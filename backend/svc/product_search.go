@@ -0,0 +1,227 @@
+package svc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultProductPageSize = 20
+	MaxProductPageSize     = 100
+)
+
+// ProductSearchVector is the full-text document for a product. It must match
+// the expression of idx_loan_products_search for the index to be used.
+const ProductSearchVector = "to_tsvector('simple', coalesce(product_name, '') || ' ' || coalesce(bank_name, ''))"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// noAPR sorts products without a parsed rate after every real rate.
+const noAPR = 9999.99
+
+// productSort is a sortable column and the same value read from a product,
+// for the cursor. Nullable columns sort through a sentinel so keyset
+// comparisons never meet NULL.
+type productSort struct {
+	expr string
+	time bool
+	key  func(p *models.LoanProduct) interface{}
+}
+
+var productSorts = map[string]productSort{
+	"created_at": {expr: "created_at", time: true, key: func(p *models.LoanProduct) interface{} { return p.CreatedAt }},
+	"updated_at": {expr: "COALESCE(updated_at, created_at)", time: true, key: func(p *models.LoanProduct) interface{} {
+		if p.UpdatedAt != nil {
+			return *p.UpdatedAt
+		}
+		return p.CreatedAt
+	}},
+	"interest_rate": {expr: "COALESCE(min_apr, 9999.99)", key: func(p *models.LoanProduct) interface{} {
+		if p.MinAPR != nil {
+			return *p.MinAPR
+		}
+		return noAPR
+	}},
+	"min_credit_score":   {expr: "min_credit_score", key: func(p *models.LoanProduct) interface{} { return p.MinCreditScore }},
+	"min_monthly_income": {expr: "min_monthly_income", key: func(p *models.LoanProduct) interface{} { return p.MinMonthlyIncome }},
+	"product_name":       {expr: "product_name", key: func(p *models.LoanProduct) interface{} { return p.ProductName }},
+	"bank_name":          {expr: "bank_name", key: func(p *models.LoanProduct) interface{} { return p.BankName }},
+}
+
+// ProductSearch filters the catalog. Nil and empty fields do not filter.
+type ProductSearch struct {
+	Query          string
+	Bank           string
+	BankID         *uuid.UUID
+	MinRate        *float64
+	MaxRate        *float64
+	MaxCreditScore *int
+	MaxIncome      *float64
+	LoanType       models.LoanType
+	Active         *bool
+	Status         models.ProductStatus
+
+	// Sort is a productSorts key, "-" prefixed for descending; the default
+	// is newest first.
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+type ProductPage struct {
+	Products   []models.LoanProduct `json:"products"`
+	Count      int                  `json:"count"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// productCursor is the keyset position after the last product of a page.
+type productCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uuid.UUID       `json:"id"`
+}
+
+// SearchProducts returns one page of products matching the search, ordered
+// by the sort column and then ID. Pages are keyset based, so products added
+// while paging do not shift later pages.
+func SearchProducts(s ProductSearch) (*ProductPage, error) {
+	if s.Sort == "" {
+		s.Sort = "-created_at"
+	}
+	sort, ok := productSorts[strings.TrimPrefix(s.Sort, "-")]
+	if !ok {
+		return nil, &ValidationError{Fields: map[string]string{"sort": "unknown sort field"}}
+	}
+	desc := strings.HasPrefix(s.Sort, "-")
+	if s.Limit <= 0 {
+		s.Limit = DefaultProductPageSize
+	}
+	s.Limit = min(s.Limit, MaxProductPageSize)
+
+	q, err := filterProducts(database.DB.Model(&models.LoanProduct{}), s)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	if s.Cursor != "" {
+		value, id, err := decodeProductCursor(s.Cursor, s.Sort, sort)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Where("("+sort.expr+", id) "+cmp+" (?, ?)", value, id)
+	}
+
+	products := []models.LoanProduct{}
+	err = q.Order(sort.expr + " " + dir).
+		Order("id " + dir).
+		Limit(s.Limit + 1).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > s.Limit {
+		page.Products = products[:s.Limit]
+		last := &page.Products[s.Limit-1]
+		value, _ := json.Marshal(sort.key(last))
+		page.NextCursor = encodeProductCursor(productCursor{Sort: s.Sort, Value: value, ID: last.ID})
+	}
+	page.Count = len(page.Products)
+	return page, nil
+}
+
+func filterProducts(q *gorm.DB, s ProductSearch) (*gorm.DB, error) {
+	if text := strings.TrimSpace(s.Query); text != "" {
+		q = q.Where(ProductSearchVector+" @@ websearch_to_tsquery('simple', ?)", text)
+	}
+	if s.BankID != nil {
+		q = q.Where("bank_id = ?", *s.BankID)
+	}
+	if bank := strings.TrimSpace(s.Bank); bank != "" {
+		// Match the crawled name, or every product of the bank it resolves
+		// to, so "HDFC" also finds products listed as "HDFC Bank Ltd.".
+		index, err := loadBankIndex(database.DB)
+		if err != nil {
+			return nil, err
+		}
+		if id := index.resolve(bank); id != nil {
+			q = q.Where("(bank_id = ? OR lower(bank_name) = lower(?))", *id, bank)
+		} else {
+			q = q.Where("lower(bank_name) = lower(?)", bank)
+		}
+	}
+	// A product's APR range must overlap the requested one.
+	if s.MinRate != nil {
+		q = q.Where("COALESCE(max_apr, min_apr) >= ?", *s.MinRate)
+	}
+	if s.MaxRate != nil {
+		q = q.Where("min_apr <= ?", *s.MaxRate)
+	}
+	if s.MaxCreditScore != nil {
+		q = q.Where("min_credit_score <= ?", *s.MaxCreditScore)
+	}
+	if s.MaxIncome != nil {
+		q = q.Where("min_monthly_income <= ?", *s.MaxIncome)
+	}
+	if s.LoanType != "" {
+		q = q.Where("loan_type = ?", s.LoanType)
+	}
+	if s.Active != nil {
+		if *s.Active {
+			q = q.Where("status = ?", models.ProductStatusActive)
+		} else {
+			q = q.Where("status <> ?", models.ProductStatusActive)
+		}
+	}
+	if s.Status != "" {
+		q = q.Where("status = ?", s.Status)
+	}
+	return q, nil
+}
+
+func encodeProductCursor(c productCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeProductCursor returns the keyset position of a cursor issued for the
+// same sort order.
+func decodeProductCursor(cursor, sortName string, sort productSort) (interface{}, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+	var c productCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != sortName {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+	if sort.time {
+		var t time.Time
+		if err := json.Unmarshal(c.Value, &t); err != nil {
+			return nil, uuid.Nil, ErrInvalidCursor
+		}
+		return t, c.ID, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(c.Value, &v); err != nil {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+	switch v.(type) {
+	case float64, string:
+		return v, c.ID, nil
+	}
+	return nil, uuid.Nil, ErrInvalidCursor
+}
@@ -0,0 +1,147 @@
+package svc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+func TestDecodeProductCursor(t *testing.T) {
+	created := time.Date(2025, 3, 1, 10, 30, 0, 123456000, time.UTC)
+	product := matchingtest.Product(func(p *models.LoanProduct) { p.CreatedAt = created })
+	cursor := func(sortName string, value string) string {
+		return encodeProductCursor(productCursor{Sort: sortName, Value: []byte(value), ID: product.ID})
+	}
+	// issued builds the cursor SearchProducts returns after product.
+	issued := func(sortName string) string {
+		raw, _ := json.Marshal(productSorts[sortName].key(product))
+		return cursor(sortName, string(raw))
+	}
+
+	tests := []struct {
+		name     string
+		cursor   string
+		sortName string
+		want     interface{}
+	}{
+		{name: "created_at", cursor: issued("created_at"), sortName: "created_at", want: created},
+		{name: "descending sort", cursor: cursor("-created_at", `"2025-03-01T10:30:00.123456Z"`), sortName: "-created_at", want: created},
+		{name: "updated_at falls back to created_at", cursor: issued("updated_at"), sortName: "updated_at", want: created},
+		{name: "interest rate", cursor: issued("interest_rate"), sortName: "interest_rate", want: 10.5},
+		{name: "no parsed rate", cursor: cursor("interest_rate", `9999.99`), sortName: "interest_rate", want: noAPR},
+		{name: "integer column", cursor: issued("min_credit_score"), sortName: "min_credit_score", want: 650.0},
+		{name: "text column", cursor: issued("product_name"), sortName: "product_name", want: "Test Personal Loan"},
+
+		{name: "other sort", cursor: issued("product_name"), sortName: "-product_name"},
+		{name: "not base64", cursor: "not a cursor!", sortName: "created_at"},
+		{name: "not JSON", cursor: base64.RawURLEncoding.EncodeToString([]byte("created_at")), sortName: "created_at"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"s":"bank_name","v":"x"}`)), sortName: "bank_name"},
+		{name: "time sort with a number", cursor: cursor("created_at", `12`), sortName: "created_at"},
+		{name: "null value", cursor: cursor("product_name", `null`), sortName: "product_name"},
+		{name: "object value", cursor: cursor("product_name", `{"name":"x"}`), sortName: "product_name"},
+		{name: "boolean value", cursor: cursor("min_credit_score", `true`), sortName: "min_credit_score"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort := productSorts[strings.TrimPrefix(tt.sortName, "-")]
+			value, id, err := decodeProductCursor(tt.cursor, tt.sortName, sort)
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Fatalf("decodeProductCursor = %v, %v, want ErrInvalidCursor", value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeProductCursor: %v", err)
+			}
+			if id != product.ID {
+				t.Errorf("id = %s, want %s", id, product.ID)
+			}
+			if want, ok := tt.want.(time.Time); ok {
+				if got, ok := value.(time.Time); !ok || !got.Equal(want) {
+					t.Errorf("value = %v, want %v", value, want)
+				}
+				return
+			}
+			if value != tt.want {
+				t.Errorf("value = %#v, want %#v", value, tt.want)
+			}
+		})
+	}
+}
+
+// TestSearchProductsPages walks every page of a search and checks that each
+// product is returned once, in order, even when products are added between
+// pages.
+func TestSearchProductsPages(t *testing.T) {
+	tx := testDB(t)
+	bank := "Paging Bank " + uuid.NewString()
+	create := func(score int) {
+		p := matchingtest.Product(func(p *models.LoanProduct) {
+			p.ID = uuid.Nil
+			p.BankName, p.MinCreditScore = bank, score
+			p.ProductURL = "https://example.com/paging/" + uuid.NewString()
+		})
+		if err := tx.Create(p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+	for _, score := range []int{700, 650, 650, 750, 650, 0, 800} {
+		create(score)
+	}
+
+	for _, sortName := range []string{"min_credit_score", "-min_credit_score", "created_at", "-interest_rate", "product_name"} {
+		t.Run(sortName, func(t *testing.T) {
+			all, err := SearchProducts(ProductSearch{Bank: bank, Sort: sortName, Limit: MaxProductPageSize})
+			if err != nil {
+				t.Fatalf("SearchProducts: %v", err)
+			}
+			if all.NextCursor != "" || all.Count < 7 {
+				t.Fatalf("single page has %d products and cursor %q, want every seeded product", all.Count, all.NextCursor)
+			}
+
+			var got []uuid.UUID
+			s := ProductSearch{Bank: bank, Sort: sortName, Limit: 2}
+			for pages := 0; ; pages++ {
+				if pages > 4 {
+					t.Fatal("paging did not finish")
+				}
+				page, err := SearchProducts(s)
+				if err != nil {
+					t.Fatalf("SearchProducts: %v", err)
+				}
+				for _, p := range page.Products {
+					got = append(got, p.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				if pages == 0 && sortName == "min_credit_score" {
+					// Sorts before the cursor, so later pages must not shift.
+					create(0)
+				}
+				s.Cursor = page.NextCursor
+			}
+			want := make([]uuid.UUID, len(all.Products))
+			for i, p := range all.Products {
+				want[i] = p.ID
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("paged ids = %v, want %v", got, want)
+			}
+		})
+	}
+
+	_, err := SearchProducts(ProductSearch{Bank: bank, Sort: "product_name", Cursor: encodeProductCursor(productCursor{Sort: "bank_name"})})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor for another sort: err = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,149 @@
+package svc
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+	"gorm.io/gorm"
+)
+
+// ProductStaleAfter is how long a product may go unseen by the crawler before
+// it is deactivated.
+var ProductStaleAfter = 14 * 24 * time.Hour
+
+// activeProducts scopes a query to products that take part in matching.
+func activeProducts() *gorm.DB {
+	return database.DB.Where("status = ?", models.ProductStatusActive)
+}
+
+// ListActiveProducts returns the products offered to users.
+func ListActiveProducts() ([]models.LoanProduct, error) {
+	var products []models.LoanProduct
+	err := activeProducts().Find(&products).Error
+	return products, err
+}
+
+// setProductStatus writes the product's status together with any extra
+// columns, and mirrors the status on the struct. Moving to active clears the
+// criteria hash and last match time, so the product watcher rematches it.
+func setProductStatus(tx *gorm.DB, product *models.LoanProduct, status models.ProductStatus, reason string, columns map[string]interface{}) error {
+	now := time.Now()
+	if columns == nil {
+		columns = map[string]interface{}{}
+	}
+	columns["status"] = status
+	columns["status_reason"] = reason
+	columns["status_changed_at"] = now
+	if status == models.ProductStatusActive {
+		columns["criteria_hash"] = ""
+		columns["last_matched_at"] = nil
+	}
+	if err := tx.Model(product).UpdateColumns(columns).Error; err != nil {
+		return err
+	}
+	product.Status = status
+	product.StatusReason = reason
+	product.StatusChangedAt = &now
+	if status == models.ProductStatusActive {
+		product.CriteriaHash = ""
+		product.LastMatchedAt = nil
+	}
+	return nil
+}
+
+// markProductSeen records that the crawler still lists the product and
+// reactivates it if it had been deactivated.
+func markProductSeen(product *models.LoanProduct) error {
+	now := time.Now()
+	columns := map[string]interface{}{"last_seen_at": now}
+	var err error
+	if product.Status == models.ProductStatusInactive {
+		err = setProductStatus(database.DB, product, models.ProductStatusActive, "seen by crawler again", columns)
+		if err == nil {
+			slog.Info("Product reactivated", "product_id", product.ID)
+		}
+	} else {
+		err = database.DB.Model(product).UpdateColumns(columns).Error
+	}
+	if err != nil {
+		slog.Error("markProductSeen: Update failed", "product_id", product.ID, "error", err)
+		return err
+	}
+	product.LastSeenAt = &now
+	return nil
+}
+
+type DeactivationResult struct {
+	Products       int `json:"products"`
+	MatchesExpired int `json:"matches_expired"`
+}
+
+// DeactivateStaleProducts marks active products not seen within window as
+// inactive and expires their active matches. Only products the crawler has
+// ingested have a last seen time; products created through the API or by an
+// admin are never deactivated for going unseen.
+func DeactivateStaleProducts(window time.Duration) (DeactivationResult, error) {
+	var result DeactivationResult
+	cutoff := time.Now().Add(-window)
+
+	var products []models.LoanProduct
+	err := activeProducts().
+		Where("last_seen_at IS NOT NULL AND last_seen_at < ?", cutoff).
+		Find(&products).Error
+	if err != nil {
+		return result, err
+	}
+
+	for i := range products {
+		p := &products[i]
+		seen := *p.LastSeenAt
+		reason := "not seen by crawler since " + seen.Format(time.RFC3339)
+
+		if err := setProductStatus(database.DB, p, models.ProductStatusInactive, reason, nil); err != nil {
+			slog.Error("DeactivateStaleProducts: Update failed", "product_id", p.ID, "error", err)
+			return result, err
+		}
+		result.Products++
+
+		n, err := expireProductMatches(p, "product inactive: "+reason)
+		if err != nil {
+			return result, err
+		}
+		result.MatchesExpired += n
+		slog.Info("Product deactivated", "product_id", p.ID, "last_seen", seen, "matches_expired", n)
+	}
+	return result, nil
+}
+
+// expireProductMatches expires every active match on the product.
+func expireProductMatches(product *models.LoanProduct, reason string) (int, error) {
+	return transitionMatches(database.DB.Where("product_id = ?", product.ID), models.MatchStatusExpired, reason)
+}
+
+// expireInactiveProductMatches expires the active matches left on products
+// that no longer take part in matching. Full match runs only visit active
+// products, so they call this to catch matches on the rest. Products held for
+// review keep their matches until the review is resolved.
+func expireInactiveProductMatches() (int, error) {
+	var statuses []models.ProductStatus
+	err := database.DB.Model(&models.LoanProduct{}).
+		Where("status NOT IN ?", []models.ProductStatus{models.ProductStatusActive, models.ProductStatusPendingReview}).
+		Distinct().Pluck("status", &statuses).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, status := range statuses {
+		products := database.DB.Model(&models.LoanProduct{}).Select("id").Where("status = ?", status)
+		n, err := transitionMatches(database.DB.Where("product_id IN (?)", products),
+			models.MatchStatusExpired, "product "+string(status))
+		if err != nil {
+			return expired, err
+		}
+		expired += n
+	}
+	return expired, nil
+}
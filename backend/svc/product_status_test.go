@@ -0,0 +1,120 @@
+package svc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BadadheVed/clickpe/models"
+	"gorm.io/gorm"
+)
+
+// seedProductState creates a product with the given status and last seen
+// time, and an active match on it.
+func seedProductState(t *testing.T, tx *gorm.DB, status models.ProductStatus, lastSeen *time.Time) (*models.LoanProduct, models.Match) {
+	t.Helper()
+	user, product := seedMatchPair(t, tx)
+	err := tx.Model(product).UpdateColumns(map[string]interface{}{
+		"status":          status,
+		"last_seen_at":    lastSeen,
+		"criteria_hash":   "matched",
+		"last_matched_at": time.Now(),
+	}).Error
+	if err != nil {
+		t.Fatalf("set product state: %v", err)
+	}
+	product.Status, product.LastSeenAt = status, lastSeen
+	match := models.Match{UserID: user.ID, ProductID: product.ID, Status: models.MatchStatusActive}
+	if err := tx.Create(&match).Error; err != nil {
+		t.Fatalf("create match: %v", err)
+	}
+	return product, match
+}
+
+func TestDeactivateStaleProducts(t *testing.T) {
+	tx := testDB(t)
+	recent, stale := time.Now().Add(-time.Hour), time.Now().Add(-3*ProductStaleAfter)
+
+	tests := []struct {
+		name            string
+		status          models.ProductStatus
+		lastSeen        *time.Time
+		wantStatus      models.ProductStatus
+		wantMatchStatus models.MatchStatus
+	}{
+		{"seen recently", models.ProductStatusActive, &recent, models.ProductStatusActive, models.MatchStatusActive},
+		{"never crawled", models.ProductStatusActive, nil, models.ProductStatusActive, models.MatchStatusActive},
+		{"unseen", models.ProductStatusActive, &stale, models.ProductStatusInactive, models.MatchStatusExpired},
+		{"unseen while under review", models.ProductStatusPendingReview, &stale, models.ProductStatusPendingReview, models.MatchStatusActive},
+		{"unseen after rejection", models.ProductStatusRejected, &stale, models.ProductStatusRejected, models.MatchStatusActive},
+	}
+	products := make([]*models.LoanProduct, len(tests))
+	matches := make([]models.Match, len(tests))
+	for i, tt := range tests {
+		products[i], matches[i] = seedProductState(t, tx, tt.status, tt.lastSeen)
+	}
+
+	result, err := DeactivateStaleProducts(ProductStaleAfter)
+	if err != nil {
+		t.Fatalf("DeactivateStaleProducts: %v", err)
+	}
+	if want := (DeactivationResult{Products: 1, MatchesExpired: 1}); result != want {
+		t.Errorf("DeactivateStaleProducts = %+v, want %+v", result, want)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var product models.LoanProduct
+			if err := tx.First(&product, "id = ?", products[i].ID).Error; err != nil {
+				t.Fatalf("load product: %v", err)
+			}
+			if product.Status != tt.wantStatus {
+				t.Errorf("product status = %s, want %s", product.Status, tt.wantStatus)
+			}
+			match := loadMatch(t, tx, matches[i].UserID, matches[i].ProductID)
+			if match.Status != tt.wantMatchStatus {
+				t.Errorf("match status = %s, want %s", match.Status, tt.wantMatchStatus)
+			}
+			if tt.wantStatus == models.ProductStatusInactive &&
+				(!strings.HasPrefix(product.StatusReason, "not seen by crawler since") ||
+					match.StatusReason != "product inactive: "+product.StatusReason) {
+				t.Errorf("reasons = %q and %q, want the last seen time on both", product.StatusReason, match.StatusReason)
+			}
+		})
+	}
+}
+
+func TestMarkProductSeen(t *testing.T) {
+	tx := testDB(t)
+	stale := time.Now().Add(-3 * ProductStaleAfter)
+	tests := []struct {
+		name          string
+		status        models.ProductStatus
+		wantStatus    models.ProductStatus
+		wantRematched bool
+	}{
+		{"active", models.ProductStatusActive, models.ProductStatusActive, false},
+		{"inactive is reactivated", models.ProductStatusInactive, models.ProductStatusActive, true},
+		{"under review", models.ProductStatusPendingReview, models.ProductStatusPendingReview, false},
+		{"rejected", models.ProductStatusRejected, models.ProductStatusRejected, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, _ := seedProductState(t, tx, tt.status, &stale)
+			if err := markProductSeen(product); err != nil {
+				t.Fatalf("markProductSeen: %v", err)
+			}
+			var got models.LoanProduct
+			if err := tx.First(&got, "id = ?", product.ID).Error; err != nil {
+				t.Fatalf("load product: %v", err)
+			}
+			if got.Status != tt.wantStatus || got.LastSeenAt == nil || !got.LastSeenAt.After(stale) {
+				t.Errorf("product is %s, last seen %v, want %s and seen now", got.Status, got.LastSeenAt, tt.wantStatus)
+			}
+			// Reactivated products are left for the product watcher to rematch.
+			if rematch := got.CriteriaHash == "" && got.LastMatchedAt == nil; rematch != tt.wantRematched {
+				t.Errorf("queued for rematch = %t, want %t", rematch, tt.wantRematched)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+func bulkInput() ProductInput {
+	return ProductInput{
+		BankName: "Bulk Bank", ProductName: "Bulk Personal Loan", LoanType: models.LoanTypePersonal, InterestRate: "10.5% - 24% p.a.",
+		MinCreditScore: 650, MinMonthlyIncome: 15000, Age: 21,
+		RawCriteria: json.RawMessage(`{"income_multiplier":2,"rules":["employment_status_salaried"]}`),
+		ProductURL:  "https://example.com/bulk/" + uuid.NewString(),
+	}
+}
+
+func TestProductFingerprint(t *testing.T) {
+	fingerprint := func(in ProductInput) string {
+		var p models.LoanProduct
+		in.Apply(&p)
+		return productFingerprint(&p)
+	}
+	base := bulkInput()
+	tests := []struct {
+		name   string
+		change func(*ProductInput)
+		same   bool
+	}{
+		{"surrounding whitespace", func(in *ProductInput) { in.BankName, in.ProductURL = " Bulk Bank ", in.ProductURL+"\n" }, true},
+		{"loan type case", func(in *ProductInput) { in.LoanType = " Personal" }, true},
+		// Postgres stores jsonb with its own key order and spacing.
+		{"raw criteria as stored", func(in *ProductInput) {
+			in.RawCriteria = json.RawMessage(`{"rules": ["employment_status_salaried"], "income_multiplier": 2}`)
+		}, true},
+		{"raw criteria changed", func(in *ProductInput) { in.RawCriteria = json.RawMessage(`{"income_multiplier":3}`) }, false},
+		{"raw criteria removed", func(in *ProductInput) { in.RawCriteria = nil }, false},
+		{"threshold", func(in *ProductInput) { in.MinCreditScore = 700 }, false},
+		{"interest rate", func(in *ProductInput) { in.InterestRate = "12% p.a." }, false},
+		{"product name", func(in *ProductInput) { in.ProductName = "Bulk Home Loan" }, false},
+		{"processing fee", func(in *ProductInput) { in.ProcessingFeePercent = 1 }, false},
+		{"service area", func(in *ProductInput) { in.ServiceableCities = []string{"Pune"} }, false},
+	}
+	want := fingerprint(base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := base
+			tt.change(&in)
+			if got := fingerprint(in); (got == want) != tt.same {
+				t.Errorf("fingerprints equal = %t, want %t", got == want, tt.same)
+			}
+		})
+	}
+}
+
+// TestUpsertProductByURL sends the same crawled product through the bulk
+// upsert path in turn and checks the outcome reported for each entry.
+func TestUpsertProductByURL(t *testing.T) {
+	testDB(t)
+	base := bulkInput()
+	with := func(change func(*ProductInput)) ProductInput {
+		in := base
+		change(&in)
+		return in
+	}
+
+	tests := []struct {
+		name    string
+		in      ProductInput
+		want    UpsertOutcome
+		invalid string
+	}{
+		{name: "new URL", in: base, want: UpsertCreated},
+		{name: "identical entry", in: base, want: UpsertUnchanged},
+		{name: "same criteria in another key order", in: with(func(in *ProductInput) {
+			in.RawCriteria = json.RawMessage(`{"rules": ["employment_status_salaried"], "income_multiplier": 2}`)
+		}), want: UpsertUnchanged},
+		{name: "changed entry", in: with(func(in *ProductInput) { in.ProductName = "Bulk Personal Loan Plus" }), want: UpsertUpdated},
+		{name: "invalid entry", in: with(func(in *ProductInput) { in.MinCreditScore = 100 }), want: UpsertRejected, invalid: "min_credit_score"},
+	}
+	var id uuid.UUID
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, outcome, err := UpsertProductByURL(tt.in, models.ProductSourceCrawler)
+			if outcome != tt.want {
+				t.Fatalf("UpsertProductByURL outcome = %s (%v), want %s", outcome, err, tt.want)
+			}
+			if tt.invalid != "" {
+				var invalid *ValidationError
+				if !errors.As(err, &invalid) || invalid.Fields[tt.invalid] == "" {
+					t.Fatalf("UpsertProductByURL error = %v, want a %s ValidationError", err, tt.invalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpsertProductByURL: %v", err)
+			}
+			if id == uuid.Nil {
+				id = product.ID
+			}
+			if product.ID != id || product.LastSeenAt == nil {
+				t.Errorf("product %s last seen %v, want %s marked seen", product.ID, product.LastSeenAt, id)
+			}
+		})
+	}
+}
+
+func TestValidateProductLoanFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*ProductInput)
+		field  string
+	}{
+		{name: "loan type normalized", change: func(in *ProductInput) { in.LoanType = " Home " }},
+		{name: "no loan type", change: func(in *ProductInput) { in.LoanType = "" }},
+		{name: "unknown loan type", change: func(in *ProductInput) { in.LoanType = "car" }, field: "loan_type"},
+		{name: "amount range", change: func(in *ProductInput) { in.MinLoanAmount, in.MaxLoanAmount = 50000, 500000 }},
+		{name: "open maximum amount", change: func(in *ProductInput) { in.MinLoanAmount = 50000 }},
+		{name: "maximum below minimum amount", change: func(in *ProductInput) { in.MinLoanAmount, in.MaxLoanAmount = 500000, 50000 }, field: "max_loan_amount"},
+		{name: "negative amount", change: func(in *ProductInput) { in.MinLoanAmount = -1 }, field: "min_loan_amount"},
+		{name: "tenure range", change: func(in *ProductInput) { in.MinTenureMonths, in.MaxTenureMonths = 12, 60 }},
+		{name: "single tenure", change: func(in *ProductInput) { in.MinTenureMonths, in.MaxTenureMonths = 36, 36 }},
+		{name: "maximum below minimum tenure", change: func(in *ProductInput) { in.MinTenureMonths, in.MaxTenureMonths = 60, 12 }, field: "max_tenure_months"},
+		{name: "tenure too long", change: func(in *ProductInput) { in.MinTenureMonths = 481 }, field: "min_tenure_months"},
+		{name: "negative tenure", change: func(in *ProductInput) { in.MaxTenureMonths = -12 }, field: "max_tenure_months"},
+		{name: "processing fee", change: func(in *ProductInput) { in.ProcessingFeePercent, in.ProcessingFeeFlat = 2.5, 999 }},
+		{name: "fee over 100%", change: func(in *ProductInput) { in.ProcessingFeePercent = 101 }, field: "processing_fee_percent"},
+		{name: "negative flat fee", change: func(in *ProductInput) { in.ProcessingFeeFlat = -1 }, field: "processing_fee_flat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := bulkInput()
+			tt.change(&in)
+			var p models.LoanProduct
+			in.Apply(&p)
+			err := ValidateProduct(&p)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("ValidateProduct: %v", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) || invalid.Fields[tt.field] == "" || len(invalid.Fields) != 1 {
+				t.Fatalf("ValidateProduct error = %v, want only %s", err, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package svc
+
+import (
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+)
+
+func GetUser(id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,263 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+
+	"github.com/BadadheVed/clickpe/database"
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/models"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// CriteriaOverrides are proposed values for a product's criteria. Nil fields
+// keep the product's current value.
+type CriteriaOverrides struct {
+	MinCreditScore   *int            `json:"min_credit_score"`
+	MinMonthlyIncome *float64        `json:"min_monthly_income"`
+	Age              *int            `json:"age"`
+	MaxFOIR          *float64        `json:"max_foir"`
+	InterestRate     *string         `json:"interest_rate"`
+	MinLoanAmount    *float64        `json:"min_loan_amount"`
+	MaxLoanAmount    *float64        `json:"max_loan_amount"`
+	MinTenureMonths  *int            `json:"min_tenure_months"`
+	MaxTenureMonths  *int            `json:"max_tenure_months"`
+	LoanType         *string         `json:"loan_type"`
+	RawCriteria      json.RawMessage `json:"raw_criteria"`
+
+	ServiceablePincodes *[]string `json:"serviceable_pincodes"`
+	ServiceableCities   *[]string `json:"serviceable_cities"`
+	CityTiers           *[]int    `json:"city_tiers"`
+}
+
+// Apply returns a copy of the product with the overrides applied.
+func (o CriteriaOverrides) Apply(p models.LoanProduct) models.LoanProduct {
+	if o.MinCreditScore != nil {
+		p.MinCreditScore = *o.MinCreditScore
+	}
+	if o.MinMonthlyIncome != nil {
+		p.MinMonthlyIncome = *o.MinMonthlyIncome
+	}
+	if o.Age != nil {
+		p.Age = *o.Age
+	}
+	if o.MaxFOIR != nil {
+		p.MaxFOIR = *o.MaxFOIR
+	}
+	if o.InterestRate != nil {
+		p.InterestRate = *o.InterestRate
+		p.ParseInterestRate()
+	}
+	if o.MinLoanAmount != nil {
+		p.MinLoanAmount = *o.MinLoanAmount
+	}
+	if o.MaxLoanAmount != nil {
+		p.MaxLoanAmount = *o.MaxLoanAmount
+	}
+	if o.MinTenureMonths != nil {
+		p.MinTenureMonths = *o.MinTenureMonths
+	}
+	if o.MaxTenureMonths != nil {
+		p.MaxTenureMonths = *o.MaxTenureMonths
+	}
+	if o.LoanType != nil {
+		p.LoanType = models.LoanType(*o.LoanType)
+	}
+	if len(o.RawCriteria) > 0 {
+		p.RawCriteria = datatypes.JSON(o.RawCriteria)
+	}
+	if o.ServiceablePincodes != nil {
+		p.ServiceablePincodes = *o.ServiceablePincodes
+	}
+	if o.ServiceableCities != nil {
+		p.ServiceableCities = *o.ServiceableCities
+	}
+	if o.CityTiers != nil {
+		p.CityTiers = *o.CityTiers
+	}
+	return p
+}
+
+// validate runs the proposed product through ValidateProduct. Problems the
+// current product already has are left out, so only what the overrides broke
+// is reported.
+func (o CriteriaOverrides) validate(current, proposed *models.LoanProduct) error {
+	err := ValidateProduct(proposed)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	var existing *ValidationError
+	if errors.As(ValidateProduct(current), &existing) {
+		for field, msg := range existing.Fields {
+			if invalid.Fields[field] == msg {
+				delete(invalid.Fields, field)
+			}
+		}
+	}
+	if len(invalid.Fields) == 0 {
+		return nil
+	}
+	return invalid
+}
+
+type WhatIfBand struct {
+	Band     string `json:"band"`
+	Baseline int    `json:"baseline"`
+	Proposed int    `json:"proposed"`
+	Gained   int    `json:"gained"`
+	Lost     int    `json:"lost"`
+}
+
+type WhatIfResult struct {
+	ProductID           uuid.UUID                 `json:"product_id"`
+	Current             matching.CriteriaSnapshot `json:"current"`
+	Proposed            matching.CriteriaSnapshot `json:"proposed"`
+	CandidatesEvaluated int                       `json:"candidates_evaluated"`
+	BaselineCount       int                       `json:"baseline_eligible"`
+	ProposedCount       int                       `json:"proposed_eligible"`
+	Delta               int                       `json:"delta"`
+	Gained              int                       `json:"gained"`
+	Lost                int                       `json:"lost"`
+	ByIncomeBand        []WhatIfBand              `json:"by_income_band"`
+	ByCreditBand        []WhatIfBand              `json:"by_credit_band"`
+}
+
+// whatIfFloor is the least credit score, income and age a user needs to pass
+// the structured checks of either product version. Evaluate only finds a user
+// eligible when every check passes, the structured credit, income and age
+// checks are always among them, and residual rules can only add checks. So
+// users below the floor are ineligible under both versions whatever the rules
+// say, and skipping them only lowers CandidatesEvaluated. A NULL age is read
+// as 0 and fails a minimum age in Go exactly as COALESCE(age, 0) does here.
+type whatIfFloor struct {
+	CreditScore   int
+	MonthlyIncome float64
+	Age           int
+}
+
+func newWhatIfFloor(current, proposed *models.LoanProduct) whatIfFloor {
+	return whatIfFloor{
+		CreditScore:   min(current.MinCreditScore, proposed.MinCreditScore),
+		MonthlyIncome: math.Min(current.MinMonthlyIncome, proposed.MinMonthlyIncome),
+		Age:           min(current.Age, proposed.Age),
+	}
+}
+
+func (f whatIfFloor) scope(db *gorm.DB) *gorm.DB {
+	return db.Where("credit_score >= ? AND monthly_income >= ? AND COALESCE(age, 0) >= ?",
+		f.CreditScore, f.MonthlyIncome, f.Age)
+}
+
+// admits is the Go form of scope.
+func (f whatIfFloor) admits(u *models.User) bool {
+	return u.CreditScore >= f.CreditScore && u.MonthlyIncome >= f.MonthlyIncome && u.Age >= f.Age
+}
+
+// SimulateCriteria evaluates the live users table against a product's current
+// and proposed criteria without persisting anything. Only users above the
+// whatIfFloor of the two versions are streamed from the database. Overrides a
+// product update would reject return a *ValidationError.
+func SimulateCriteria(product *models.LoanProduct, overrides CriteriaOverrides) (*WhatIfResult, error) {
+	proposed := overrides.Apply(*product)
+	if err := overrides.validate(product, &proposed); err != nil {
+		return nil, err
+	}
+	result := &WhatIfResult{
+		ProductID: product.ID,
+		Current:   matching.Snapshot(product),
+		Proposed:  matching.Snapshot(&proposed),
+	}
+
+	income := newWhatIfBands(incomeBands)
+	credit := newWhatIfBands(creditBands)
+
+	var users []models.User
+	err := newWhatIfFloor(product, &proposed).scope(database.DB).
+		FindInBatches(&users, matchBatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range users {
+				u := &users[i]
+				before := matching.Evaluate(u, product).Eligible
+				after := matching.Evaluate(u, &proposed).Eligible
+				result.CandidatesEvaluated++
+				if !before && !after {
+					continue
+				}
+				income.add(bandFor(incomeBands, u.MonthlyIncome), before, after)
+				credit.add(bandFor(creditBands, float64(u.CreditScore)), before, after)
+				result.tally(before, after)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		slog.Error("SimulateCriteria: Failed", "product_id", product.ID, "error", err)
+		return nil, err
+	}
+
+	result.Delta = result.ProposedCount - result.BaselineCount
+	result.ByIncomeBand = income.list()
+	result.ByCreditBand = credit.list()
+	return result, nil
+}
+
+func (r *WhatIfResult) tally(before, after bool) {
+	if before {
+		r.BaselineCount++
+	}
+	if after {
+		r.ProposedCount++
+	}
+	if after && !before {
+		r.Gained++
+	}
+	if before && !after {
+		r.Lost++
+	}
+}
+
+type whatIfBands struct {
+	order []string
+	byKey map[string]*WhatIfBand
+}
+
+func newWhatIfBands(bands []band) *whatIfBands {
+	w := &whatIfBands{byKey: map[string]*WhatIfBand{}}
+	for _, label := range bandLabels(bands) {
+		w.order = append(w.order, label)
+		w.byKey[label] = &WhatIfBand{Band: label}
+	}
+	return w
+}
+
+func (w *whatIfBands) add(label string, before, after bool) {
+	b, ok := w.byKey[label]
+	if !ok {
+		b = &WhatIfBand{Band: label}
+		w.byKey[label] = b
+		w.order = append(w.order, label)
+	}
+	if before {
+		b.Baseline++
+	}
+	if after {
+		b.Proposed++
+	}
+	if after && !before {
+		b.Gained++
+	}
+	if before && !after {
+		b.Lost++
+	}
+}
+
+func (w *whatIfBands) list() []WhatIfBand {
+	out := make([]WhatIfBand, 0, len(w.order))
+	for _, label := range w.order {
+		out = append(out, *w.byKey[label])
+	}
+	return out
+}
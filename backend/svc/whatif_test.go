@@ -0,0 +1,147 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/BadadheVed/clickpe/matching"
+	"github.com/BadadheVed/clickpe/matching/matchingtest"
+	"github.com/BadadheVed/clickpe/models"
+)
+
+// TestWhatIfFloorSkipsOnlyIneligibleUsers checks the premise of the what-if
+// prefilter: a user below the floor is ineligible under both product versions,
+// whatever residual rules either version carries.
+func TestWhatIfFloorSkipsOnlyIneligibleUsers(t *testing.T) {
+	anyEmployment := matchingtest.WithRules(matchingtest.Ref("employment_status", map[string]any{"allowed": []string{}}))
+	pairs := []struct {
+		name              string
+		current, proposed *models.LoanProduct
+	}{
+		{
+			name:     "lower thresholds",
+			current:  matchingtest.Product(),
+			proposed: matchingtest.Product(func(p *models.LoanProduct) { p.MinCreditScore, p.MinMonthlyIncome, p.Age = 600, 10000, 18 }),
+		},
+		{
+			name:     "raise thresholds",
+			current:  matchingtest.Product(),
+			proposed: matchingtest.Product(func(p *models.LoanProduct) { p.MinCreditScore, p.MinMonthlyIncome, p.Age = 750, 40000, 25 }),
+		},
+		{
+			name:     "drop minimum age, permissive rule",
+			current:  matchingtest.Product(anyEmployment),
+			proposed: matchingtest.Product(anyEmployment, func(p *models.LoanProduct) { p.Age = 0 }),
+		},
+		{
+			name:    "rules on both versions",
+			current: matchingtest.Product(matchingtest.WithRules(matchingtest.Ref("max_age", map[string]int{"max": 60}))),
+			proposed: matchingtest.Product(matchingtest.WithRules(
+				matchingtest.Ref("employment_status_salaried", nil),
+				matchingtest.Ref("foir", map[string]float64{"max": 0.6}),
+			)),
+		},
+	}
+
+	for _, pair := range pairs {
+		t.Run(pair.name, func(t *testing.T) {
+			floor := newWhatIfFloor(pair.current, pair.proposed)
+			skipped := 0
+			for _, credit := range []int{0, 599, 600, 649, 650, 749, 750, 900} {
+				for _, income := range []float64{0, 9999, 10000, 14999, 15000, 39999, 40000, 100000} {
+					// Age 0 is how a NULL age is read.
+					for _, age := range []int{0, 17, 18, 20, 21, 24, 25, 45, 70} {
+						u := matchingtest.User(func(u *models.User) { u.CreditScore, u.MonthlyIncome, u.Age = credit, income, age })
+						if floor.admits(u) {
+							continue
+						}
+						skipped++
+						for _, p := range []*models.LoanProduct{pair.current, pair.proposed} {
+							if res := matching.Evaluate(u, p); res.Eligible {
+								t.Errorf("user credit=%d income=%.0f age=%d is below the floor %+v but eligible: %s",
+									credit, income, age, floor, res.Reason())
+							}
+						}
+					}
+				}
+			}
+			if skipped == 0 {
+				t.Fatal("no user fell below the floor; the grid does not exercise the prefilter")
+			}
+		})
+	}
+}
+
+func TestWhatIfFloorNullAge(t *testing.T) {
+	current := matchingtest.Product(func(p *models.LoanProduct) { p.Age = 21 })
+	unknownAge := matchingtest.User(func(u *models.User) { u.Age = 0 })
+
+	tests := []struct {
+		name     string
+		proposed *models.LoanProduct
+		admitted bool
+	}{
+		{"both versions set a minimum age", matchingtest.Product(func(p *models.LoanProduct) { p.Age = 18 }), false},
+		{"proposed version drops the minimum age", matchingtest.Product(func(p *models.LoanProduct) { p.Age = 0 }), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newWhatIfFloor(current, tt.proposed).admits(unknownAge); got != tt.admitted {
+				t.Fatalf("admits = %t, want %t", got, tt.admitted)
+			}
+			matchingtest.AssertEligible(t, unknownAge, tt.proposed, tt.admitted)
+		})
+	}
+}
+
+func TestCriteriaOverridesValidate(t *testing.T) {
+	intp := func(v int) *int { return &v }
+	floatp := func(v float64) *float64 { return &v }
+	bounded := func(p *models.LoanProduct) { p.MinLoanAmount, p.MaxLoanAmount = 50000, 500000 }
+
+	tests := []struct {
+		name      string
+		product   *models.LoanProduct
+		overrides CriteriaOverrides
+		fields    []string
+	}{
+		{name: "valid", product: matchingtest.Product(), overrides: CriteriaOverrides{MinCreditScore: intp(700), MaxFOIR: floatp(0.5)}},
+		{name: "credit score out of range", product: matchingtest.Product(), overrides: CriteriaOverrides{MinCreditScore: intp(200)}, fields: []string{"min_credit_score"}},
+		{name: "negative income", product: matchingtest.Product(), overrides: CriteriaOverrides{MinMonthlyIncome: floatp(-1)}, fields: []string{"min_monthly_income"}},
+		{name: "unknown rule", product: matchingtest.Product(), overrides: CriteriaOverrides{RawCriteria: json.RawMessage(`{"rules": ["salaired"]}`)}, fields: []string{"raw_criteria"}},
+		{name: "minimum above current maximum", product: matchingtest.Product(bounded), overrides: CriteriaOverrides{MinLoanAmount: floatp(600000)}, fields: []string{"max_loan_amount"}},
+		{
+			name:      "existing problems are not reported",
+			product:   matchingtest.Product(func(p *models.LoanProduct) { p.ProductURL = "" }),
+			overrides: CriteriaOverrides{Age: intp(25)},
+		},
+		{
+			name:      "existing problems do not hide new ones",
+			product:   matchingtest.Product(func(p *models.LoanProduct) { p.ProductURL = "" }),
+			overrides: CriteriaOverrides{Age: intp(12)},
+			fields:    []string{"age"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proposed := tt.overrides.Apply(*tt.product)
+			err := tt.overrides.validate(tt.product, &proposed)
+			var got []string
+			var invalid *ValidationError
+			if errors.As(err, &invalid) {
+				for field := range invalid.Fields {
+					got = append(got, field)
+				}
+				slices.Sort(got)
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Errorf("invalid fields = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}